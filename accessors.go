@@ -4,6 +4,19 @@ import (
 	"strings"
 )
 
+// Requires finds a requirement or hint by class, including classes
+// registered with RegisterRequirement. Requirements take precedence over hints.
+func (t *Tool) Requires(class string) (Requirement, bool) {
+	reqs := append([]Requirement{}, t.Requirements...)
+	reqs = append(reqs, t.Hints...)
+	for _, req := range reqs {
+		if strings.EqualFold(RequirementClass(req), class) {
+			return req, true
+		}
+	}
+	return nil, false
+}
+
 func (t *Tool) RequiresDocker() (*DockerRequirement, bool) {
 	reqs := append([]Requirement{}, t.Requirements...)
 	reqs = append(reqs, t.Hints...)
@@ -37,6 +50,17 @@ func (t *Tool) RequiresInlineJavascript() ([]string, bool) {
 	return nil, false
 }
 
+func (t *Tool) RequiresToolTimeLimit() (*ToolTimeLimit, bool) {
+	reqs := append([]Requirement{}, t.Requirements...)
+	reqs = append(reqs, t.Hints...)
+	for _, req := range reqs {
+		if r, ok := req.(ToolTimeLimit); ok {
+			return &r, true
+		}
+	}
+	return nil, false
+}
+
 func (t *Tool) RequiresSchemaDef() (*SchemaDefRequirement, bool) {
 	reqs := append([]Requirement{}, t.Requirements...)
 	reqs = append(reqs, t.Hints...)
@@ -144,9 +168,11 @@ func (clb *CommandLineBinding) GetLoadContents() bool {
 	return clb.LoadContents
 }
 
-func (clb *CommandLineBinding) GetPosition() int {
+// GetPosition returns the position, which is an int
+// or, since v1.1, an expression.
+func (clb *CommandLineBinding) GetPosition() Expression {
 	if clb == nil {
-		return 0
+		return ""
 	}
 	return clb.Position
 }
@@ -0,0 +1,553 @@
+package cwl
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The files of a bundle, written by Bundle.
+const (
+	// BundleDocument is the packed document.
+	BundleDocument = "workflow.cwl"
+	// BundleJob is the input values.
+	BundleJob = "job.json"
+)
+
+// Bundle writes a gzipped tar archive of a document, with the input values
+// "job", so that it can be moved and run elsewhere, such as on an isolated
+// network. The archive contains:
+//
+//   - the document, packed with the documents it runs (see Pack),
+//     as BundleDocument. "$import" and "$include" are already resolved.
+//   - the input values, as BundleJob.
+//   - the files and directories of the input values and of the default
+//     values of inputs, under "files/".
+//
+// The locations of the files are rewritten relative to the root of the
+// archive. Locations in default values are relative to the document which
+// declares them, and locations in "job" are relative to "jobDir". Files are
+// read with "r", or DefaultResolver if nil. Directories must be local.
+//
+// Files are written to the archive as they're found, keeping the modes
+// of local files. With DefaultResolver, local files are copied without
+// reading them into memory.
+//
+// Bundles are read by OpenArchive.
+func Bundle(w io.Writer, doc Document, job Values, jobDir string, r Resolver) error {
+	if r == nil {
+		r = DefaultResolver{}
+	}
+	gz := gzip.NewWriter(w)
+	b := &bundler{resolver: r, names: map[string]string{}, tw: tar.NewWriter(gz)}
+
+	doc, err := b.doc(doc, "")
+	if err != nil {
+		return err
+	}
+	g, err := Pack(doc)
+	if err != nil {
+		return err
+	}
+	docBytes, err := MarshalYAML(g)
+	if err != nil {
+		return err
+	}
+
+	vals := Values{}
+	for k, v := range job {
+		x, err := b.value(v, jobDir)
+		if err != nil {
+			return errf("input %q: %s", k, err)
+		}
+		vals[k] = x
+	}
+	jobBytes, err := json.MarshalIndent(vals, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The documents are written last, since the names
+	// of the files are known once they're written.
+	if err := b.write(BundleDocument, 0644, int64(len(docBytes)), bytes.NewReader(docBytes)); err != nil {
+		return err
+	}
+	if err := b.write(BundleJob, 0644, int64(len(jobBytes)), bytes.NewReader(jobBytes)); err != nil {
+		return err
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+type bundler struct {
+	resolver Resolver
+	// names maps the location of each bundled file
+	// or directory to its name in the archive.
+	names map[string]string
+	tw    *tar.Writer
+}
+
+// doc copies a document, rewriting the files of its default values.
+// "base" is the location which references in the document are relative to.
+func (b *bundler) doc(doc Document, base string) (Document, error) {
+	if f := sourceFile(doc); f != "" {
+		base = docBase(f)
+	}
+
+	switch z := doc.(type) {
+	case *Tool:
+		c := *z
+		inputs, err := b.inputs(z.Inputs, base)
+		if err != nil {
+			return nil, err
+		}
+		c.Inputs = inputs
+		return &c, nil
+
+	case *ExpressionTool:
+		c := *z
+		inputs, err := b.inputs(z.Inputs, base)
+		if err != nil {
+			return nil, err
+		}
+		c.Inputs = inputs
+		return &c, nil
+
+	case *Workflow:
+		c := *z
+		c.Inputs = append([]WorkflowInput(nil), z.Inputs...)
+		for i := range c.Inputs {
+			in := &c.Inputs[i]
+			v, err := b.value(in.Default, base)
+			if err != nil {
+				return nil, errf("input %q: %s", in.ID, err)
+			}
+			in.Default = v
+		}
+
+		c.Steps = append([]Step(nil), z.Steps...)
+		for i := range c.Steps {
+			step := &c.Steps[i]
+			step.In = append([]StepInput(nil), step.In...)
+			for j := range step.In {
+				in := &step.In[j]
+				v, err := b.value(in.Default, base)
+				if err != nil {
+					return nil, errf("step %q: input %q: %s", step.ID, in.ID, err)
+				}
+				in.Default = v
+			}
+			if step.Run == nil {
+				continue
+			}
+			run, err := b.doc(step.Run, base)
+			if err != nil {
+				return nil, errf("step %q: %s", step.ID, err)
+			}
+			step.Run = run
+		}
+		return &c, nil
+	}
+	return doc, nil
+}
+
+func (b *bundler) inputs(inputs []CommandInput, base string) ([]CommandInput, error) {
+	inputs = append([]CommandInput(nil), inputs...)
+	for i := range inputs {
+		v, err := b.value(inputs[i].Default, base)
+		if err != nil {
+			return nil, errf("input %q: %s", inputs[i].ID, err)
+		}
+		inputs[i].Default = v
+	}
+	return inputs, nil
+}
+
+// value copies a value, bundling its files and directories.
+func (b *bundler) value(v Value, base string) (Value, error) {
+	switch z := v.(type) {
+	case File:
+		return b.file(z, base)
+	case Directory:
+		return b.dir(z, base)
+	case []Value:
+		out := make([]Value, len(z))
+		for i, x := range z {
+			y, err := b.value(x, base)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = y
+		}
+		return out, nil
+	case map[string]Value:
+		out := map[string]Value{}
+		for k, x := range z {
+			y, err := b.value(x, base)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = y
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (b *bundler) file(f File, base string) (File, error) {
+	loc := f.Location
+	if loc == "" {
+		loc = f.Path
+	}
+	if loc != "" {
+		loc = localPath(loc)
+		key := docLocation(base, loc)
+		name, ok := b.names[key]
+		if !ok {
+			name = b.name(key)
+			if err := b.copyFile(name, key, base, loc); err != nil {
+				return f, err
+			}
+		}
+		f.Location, f.Path = name, ""
+	}
+
+	sec, err := b.listing(f.SecondaryFiles, base)
+	if err != nil {
+		return f, err
+	}
+	f.SecondaryFiles = sec
+	return f, nil
+}
+
+func (b *bundler) dir(d Directory, base string) (Directory, error) {
+	loc := d.Location
+	if loc == "" {
+		loc = d.Path
+	}
+	// A literal directory, with only a listing.
+	if loc == "" {
+		listing, err := b.listing(d.Listing, base)
+		if err != nil {
+			return d, err
+		}
+		d.Listing = listing
+		return d, nil
+	}
+
+	loc = localPath(loc)
+	root := docLocation(base, loc)
+	if _, ok := isHTTP(base, loc); ok {
+		return d, errf("can't bundle directory %s, only local directories", root)
+	}
+	if _, ok := isGit(base, loc); ok {
+		return d, errf("can't bundle directory %s, only local directories", root)
+	}
+
+	name, ok := b.names[root]
+	if !ok {
+		name = b.name(root)
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			file := path.Join(name, filepath.ToSlash(rel))
+			if info.IsDir() {
+				return b.writeDir(file, info.Mode())
+			}
+			return b.writeLocal(file, p)
+		})
+		if err != nil {
+			return d, errf("failed to bundle directory %s: %s", root, err)
+		}
+	}
+	d.Location, d.Path, d.Listing = name, "", nil
+	return d, nil
+}
+
+// copyFile writes the file at "loc" to the archive as "name".
+// "key" is its location, used in errors.
+func (b *bundler) copyFile(name, key, base, loc string) error {
+	_, local := b.resolver.(DefaultResolver)
+	if _, ok := isHTTP(base, loc); ok {
+		local = false
+	}
+	if _, ok := isGit(base, loc); ok {
+		local = false
+	}
+	if local {
+		if err := b.writeLocal(name, key); err != nil {
+			return errf("failed to bundle file %s: %s", key, err)
+		}
+		return nil
+	}
+
+	data, _, err := b.resolver.Resolve(base, loc)
+	if err != nil {
+		return errf("failed to resolve file %s: %s", key, err)
+	}
+	return b.write(name, 0644, int64(len(data)), bytes.NewReader(data))
+}
+
+// writeLocal copies the local file at "p" to the archive, with its mode.
+func (b *bundler) writeLocal(name, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return b.write(name, info.Mode(), info.Size(), f)
+}
+
+// write writes a file of "size" bytes, read from "r", to the archive.
+func (b *bundler) write(name string, mode os.FileMode, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode.Perm()),
+		Size:    size,
+		ModTime: bundleTime,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(b.tw, r)
+	return err
+}
+
+func (b *bundler) writeDir(name string, mode os.FileMode) error {
+	return b.tw.WriteHeader(&tar.Header{
+		Name:     name + "/",
+		Typeflag: tar.TypeDir,
+		Mode:     int64(mode.Perm()),
+		ModTime:  bundleTime,
+	})
+}
+
+// bundleTime is the time of every file in a bundle, so that
+// bundling the same files gives the same archive.
+var bundleTime = time.Unix(0, 0)
+
+func (b *bundler) listing(list []FileDir, base string) ([]FileDir, error) {
+	if list == nil {
+		return nil, nil
+	}
+	out := make([]FileDir, len(list))
+	for i, x := range list {
+		y, err := b.value(x, base)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = y.(FileDir)
+	}
+	return out, nil
+}
+
+// name returns the name in the archive of the file at "key",
+// such as "files/1/reads.fastq".
+func (b *bundler) name(key string) string {
+	base := path.Base(filepath.ToSlash(key))
+	if u, err := url.Parse(key); err == nil && u.IsAbs() {
+		base = path.Base(u.Path)
+	}
+	name := fmt.Sprintf("files/%d/%s", len(b.names)+1, base)
+	b.names[key] = name
+	return name
+}
+
+// docBase returns the location which the references in
+// the document at "file" are relative to.
+func docBase(file string) string {
+	if gl, ok := parseGitLocation(file); ok {
+		gl.path = path.Dir(gl.path)
+		return gl.String()
+	}
+	if _, ok := isHTTP("", file); ok {
+		return file
+	}
+	return filepath.Dir(file)
+}
+
+// localPath converts a "file://" URI to a path.
+func localPath(loc string) string {
+	if strings.HasPrefix(loc, "file://") {
+		if u, err := url.Parse(loc); err == nil {
+			return u.Path
+		}
+	}
+	return loc
+}
+
+// Archive is a bundle written by Bundle. It resolves the documents in the
+// bundle, so that the bundle can be loaded without extracting it:
+//
+//	a, err := OpenArchive("run.tar.gz")
+//	doc, err := LoadWithResolver(BundleDocument, a)
+//
+// Locations are resolved like FSResolver. Only the documents, BundleDocument
+// and BundleJob, are kept in memory; the other files are read by Extract.
+type Archive struct {
+	// open opens the archive from the start, to extract it.
+	open func() (io.ReadCloser, error)
+	docs map[string][]byte
+}
+
+// OpenArchive reads a bundle from a file.
+func OpenArchive(path string) (*Archive, error) {
+	a := &Archive{open: func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}}
+	if err := a.readDocs(); err != nil {
+		return nil, errf("failed to read bundle %s: %s", path, err)
+	}
+	return a, nil
+}
+
+// ReadArchive reads a bundle, a gzipped tar archive, from its current
+// position. Extract seeks back to that position to read the files.
+func ReadArchive(r io.ReadSeeker) (*Archive, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{open: func() (io.ReadCloser, error) {
+		if _, err := r.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(r), nil
+	}}
+	if err := a.readDocs(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Archive) readDocs() error {
+	a.docs = map[string][]byte{}
+	err := a.walk(func(hdr *tar.Header, name string, r io.Reader) error {
+		if hdr.Typeflag != tar.TypeReg || (name != BundleDocument && name != BundleJob) {
+			return nil
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		a.docs[name] = b
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := a.docs[BundleDocument]; !ok {
+		return errf("%s is missing", BundleDocument)
+	}
+	return nil
+}
+
+// walk calls "fn" with each entry in the archive, and its name.
+func (a *Archive) walk(fn func(hdr *tar.Header, name string, r io.Reader) error) error {
+	rc, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(hdr.Name, "/")
+		// Names such as "../x" would be extracted outside of the directory.
+		if !fs.ValidPath(name) {
+			return errf("invalid file name %q", hdr.Name)
+		}
+		if err := fn(hdr, name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Archive) Resolve(base, loc string) ([]byte, string, error) {
+	if u, ok := isHTTP(base, loc); ok {
+		return nil, "", errf("%s isn't in the bundle", u)
+	}
+	p, err := fsPath(base, loc)
+	if err != nil {
+		return nil, "", err
+	}
+	b, ok := a.docs[p]
+	if !ok {
+		return nil, "", errf("%s isn't a document in the bundle", p)
+	}
+	return b, path.Dir(p), nil
+}
+
+// Job returns the input values of the bundle. Their locations are
+// relative to the directory the bundle is extracted to.
+func (a *Archive) Job() (Values, error) {
+	b, ok := a.docs[BundleJob]
+	if !ok {
+		return Values{}, nil
+	}
+	return LoadValuesBytes(b)
+}
+
+// Extract writes the files of the bundle to a directory,
+// with the modes they were bundled with.
+func (a *Archive) Extract(dir string) error {
+	return a.walk(func(hdr *tar.Header, name string, r io.Reader) error {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		mode := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+			return os.Chmod(p, mode|0700)
+
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, r)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		}
+		return nil
+	})
+}
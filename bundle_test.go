@@ -0,0 +1,171 @@
+package cwl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBundle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"wf.cwl": {Data: []byte(`
+cwlVersion: v1.0
+class: Workflow
+inputs:
+  ref:
+    type: File
+    default:
+      class: File
+      location: data/ref.txt
+outputs: []
+steps:
+  a:
+    run: tools/a.cwl
+    in:
+      ref: ref
+    out: []
+`)},
+		"tools/a.cwl": {Data: []byte(`
+cwlVersion: v1.0
+class: CommandLineTool
+inputs:
+  ref: File
+  config:
+    type: File
+    default:
+      class: File
+      location: config.txt
+outputs: []
+`)},
+		"data/ref.txt":      {Data: []byte("ref")},
+		"tools/config.txt":  {Data: []byte("config")},
+		"job/reads.txt":     {Data: []byte("reads")},
+		"job/reads.txt.idx": {Data: []byte("index")},
+	}
+	r := FSResolver{FS: fsys}
+	doc, err := LoadWithResolver("wf.cwl", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := Values{
+		"reads": File{
+			Location:       "reads.txt",
+			SecondaryFiles: []FileDir{File{Location: "reads.txt.idx"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Bundle(&buf, doc, job, "job", r); err != nil {
+		t.Fatal(err)
+	}
+	a, err := ReadArchive(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "cwl-bundle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := a.Extract(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundled files are referred to relative to the root of the bundle.
+	read := func(loc string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, loc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	bundled, err := LoadWithResolver(BundleDocument, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := bundled.(*Workflow)
+	if got := read(wf.Inputs[0].Default.(File).Location); got != "ref" {
+		t.Errorf("unexpected workflow default: %q", got)
+	}
+	tool := wf.Steps[0].Run.(*Tool)
+	if got := read(tool.Inputs[1].Default.(File).Location); got != "config" {
+		t.Errorf("unexpected tool default: %q", got)
+	}
+	if doc.(*Workflow).Inputs[0].Default.(File).Location != "data/ref.txt" {
+		t.Error("expected the original document to be unchanged")
+	}
+
+	vals, err := a.Job()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads := vals["reads"].(File)
+	if got := read(reads.Location); got != "reads" {
+		t.Errorf("unexpected job file: %q", got)
+	}
+	if got := read(reads.SecondaryFiles[0].(File).Location); got != "index" {
+		t.Errorf("unexpected secondary file: %q", got)
+	}
+
+	// Only the documents are resolved from the archive.
+	if _, _, err := a.Resolve("", reads.Location); err == nil {
+		t.Error("expected an error resolving a file which isn't a document")
+	}
+}
+
+func TestBundleDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-bundle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "refs", "empty"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "refs", "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "refs", "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "tool.sh"), []byte("#!/bin/sh\n"), 0755)
+
+	job := Values{
+		"refs":   Directory{Location: "refs"},
+		"script": File{Location: "tool.sh"},
+	}
+	var buf bytes.Buffer
+	if err := Bundle(&buf, &Tool{CWLVersion: "v1.0"}, job, dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	a, err := ReadArchive(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := a.Job()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := vals["refs"].(Directory).Location
+
+	out := filepath.Join(dir, "out")
+	if err := a.Extract(out); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(out, loc, "a.txt")); err != nil || string(b) != "a" {
+		t.Errorf("unexpected extracted file: %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, loc, "empty")); err != nil {
+		t.Errorf("expected the empty directory to be extracted: %v", err)
+	}
+
+	// Modes are kept, e.g. scripts stay executable.
+	for _, p := range []string{filepath.Join(loc, "run.sh"), vals["script"].(File).Location} {
+		info, err := os.Stat(filepath.Join(out, p))
+		if err != nil || info.Mode().Perm()&0100 == 0 {
+			t.Errorf("expected %s to be executable: %v %v", p, info, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(out, loc, "a.txt")); err != nil || info.Mode().Perm()&0100 != 0 {
+		t.Errorf("expected a.txt not to be executable: %v %v", info, err)
+	}
+}
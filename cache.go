@@ -0,0 +1,162 @@
+package cwl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// CachingResolver resolves documents with Resolver, storing the documents
+// it resolves from HTTP(S) URLs on disk, keyed by URL. Other locations,
+// such as local files, aren't cached.
+//
+// If Resolver can revalidate a document, as DefaultResolver can, cached
+// documents are revalidated with the ETag and Last-Modified headers, so
+// unchanged documents aren't downloaded again. Otherwise, documents are
+// resolved again, and the cache is only read when Offline is set.
+type CachingResolver struct {
+	// Resolver resolves the documents which are cached.
+	// If nil, DefaultResolver is used.
+	Resolver Resolver
+	// Dir is the cache directory, created if needed.
+	Dir string
+	// Offline serves documents only from the cache, without network access.
+	// Documents which aren't cached fail to resolve.
+	Offline bool
+}
+
+// revalidator is implemented by resolvers which can fetch
+// a document only if it changed since it was cached.
+type revalidator interface {
+	// revalidate fetches the document at "u", unless it's unchanged
+	// since "v" was returned with it, in which case "modified" is false.
+	revalidate(u *url.URL, v validators) (doc []byte, next validators, modified bool, err error)
+}
+
+// validators identify the version of a document fetched over HTTP.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// cacheEntry is stored next to a cached document.
+type cacheEntry struct {
+	URL string `json:"url"`
+	// Base is the base returned by the resolver with the document.
+	Base string `json:"base"`
+	validators
+}
+
+func (c *CachingResolver) Resolve(base, loc string) ([]byte, string, error) {
+	r := c.Resolver
+	if r == nil {
+		r = DefaultResolver{}
+	}
+	u, ok := isHTTP(base, loc)
+	if !ok || (u.Scheme != "http" && u.Scheme != "https") {
+		return r.Resolve(base, loc)
+	}
+	// The fragment isn't part of the document.
+	u.Fragment = ""
+	key := u.String()
+
+	body, entry, cached := c.load(key)
+	if c.Offline {
+		if !cached {
+			return nil, "", errf("%s isn't cached, and resolving is offline", key)
+		}
+		return body, entry.Base, nil
+	}
+
+	rv, ok := r.(revalidator)
+	if !ok {
+		b, newBase, err := r.Resolve(base, loc)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := c.store(key, b, cacheEntry{URL: key, Base: newBase}); err != nil {
+			return nil, "", errf("caching %s: %s", key, err)
+		}
+		return b, newBase, nil
+	}
+
+	var v validators
+	if cached {
+		v = entry.validators
+	}
+	b, next, modified, err := rv.revalidate(u, v)
+	if err != nil {
+		return nil, "", err
+	}
+	if !modified {
+		return body, entry.Base, nil
+	}
+	if err := c.store(key, b, cacheEntry{URL: key, Base: key, validators: next}); err != nil {
+		return nil, "", errf("caching %s: %s", key, err)
+	}
+	return b, key, nil
+}
+
+// path returns the path of a cached document, without an extension.
+func (c *CachingResolver) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// load returns a cached document, if it's in the cache.
+func (c *CachingResolver) load(key string) ([]byte, cacheEntry, bool) {
+	var entry cacheEntry
+	p := c.path(key)
+	meta, err := ioutil.ReadFile(p + ".json")
+	if err != nil {
+		return nil, entry, false
+	}
+	if json.Unmarshal(meta, &entry) != nil || entry.URL != key {
+		return nil, entry, false
+	}
+	body, err := ioutil.ReadFile(p + ".cwl")
+	if err != nil {
+		return nil, entry, false
+	}
+	return body, entry, true
+}
+
+// store writes a document to the cache. The document is written before
+// its entry, so that a partly written document is never served.
+func (c *CachingResolver) store(key string, body []byte, entry cacheEntry) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	p := c.path(key)
+	os.Remove(p + ".json")
+	if err := writeFileAtomic(p+".cwl", body); err != nil {
+		return err
+	}
+	return writeFileAtomic(p+".json", meta)
+}
+
+// writeFileAtomic writes a file via a temporary file, so that readers
+// never see a partly written file.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,157 @@
+package cwl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const cacheTestTool = `
+cwlVersion: v1.0
+class: CommandLineTool
+inputs: []
+outputs: []
+`
+
+func TestCachingResolver(t *testing.T) {
+	var requests, downloads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/tool.cwl" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(cacheTestTool))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "cwl-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &CachingResolver{Dir: dir}
+	for i := 0; i < 2; i++ {
+		d, err := LoadWithResolver(srv.URL+"/tool.cwl", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := d.(*Tool); !ok {
+			t.Fatalf("expected a tool, got %T", d)
+		}
+	}
+	if requests != 2 || downloads != 1 {
+		t.Errorf("expected 2 requests and 1 download, got %d and %d", requests, downloads)
+	}
+
+	// A 404 page isn't parsed as a document.
+	_, err = LoadWithResolver(srv.URL+"/missing.cwl", r)
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+	_, err = LoadWithResolver(srv.URL+"/missing.cwl", DefaultResolver{})
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected a 404 error from the default resolver, got %v", err)
+	}
+
+	// Offline, documents are served from the cache only.
+	srv.Close()
+	offline := &CachingResolver{Dir: dir, Offline: true}
+	if _, err := LoadWithResolver(srv.URL+"/tool.cwl", offline); err != nil {
+		t.Errorf("expected the cached document offline, got %v", err)
+	}
+	_, err = LoadWithResolver(srv.URL+"/other.cwl", offline)
+	if err == nil || !strings.Contains(err.Error(), "isn't cached") {
+		t.Errorf("expected an offline error, got %v", err)
+	}
+}
+
+func TestCachingResolverLastModified(t *testing.T) {
+	const modified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	var downloads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == modified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("Last-Modified", modified)
+		w.Write([]byte(cacheTestTool))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "cwl-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &CachingResolver{Dir: dir}
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.Resolve("", srv.URL+"/tool.cwl#main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("expected 1 download, got %d", downloads)
+	}
+}
+
+// memResolver resolves documents from a map, counting the calls.
+type memResolver struct {
+	docs  map[string]string
+	calls int
+}
+
+func (m *memResolver) Resolve(base, loc string) ([]byte, string, error) {
+	m.calls++
+	u, ok := isHTTP(base, loc)
+	if !ok {
+		return nil, "", errf("%s isn't a URL", loc)
+	}
+	u.Fragment = ""
+	d, ok := m.docs[u.String()]
+	if !ok {
+		return nil, "", errf("%s not found", u)
+	}
+	return []byte(d), u.String(), nil
+}
+
+func TestCachingResolverWraps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const loc = "https://example.com/tools/tool.cwl"
+	m := &memResolver{docs: map[string]string{loc: cacheTestTool}}
+	r := &CachingResolver{Resolver: m, Dir: dir}
+	if _, err := LoadWithResolver(loc, r); err != nil {
+		t.Fatal(err)
+	}
+
+	// Documents from a resolver which can't revalidate are resolved again.
+	if _, _, err := r.Resolve("", loc); err != nil || m.calls != 2 {
+		t.Errorf("expected the document to be resolved again, got %d calls, %v", m.calls, err)
+	}
+
+	offline := &CachingResolver{Resolver: m, Dir: dir, Offline: true}
+	b, base, err := offline.Resolve(loc, "tool.cwl#main")
+	if err != nil || string(b) != cacheTestTool || base != loc {
+		t.Errorf("expected the cached document offline, got %q, %q, %v", b, base, err)
+	}
+	if m.calls != 2 {
+		t.Errorf("expected no calls offline, got %d", m.calls-2)
+	}
+}
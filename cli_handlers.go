@@ -103,7 +103,11 @@ func (l *loader) SeqToCommandOutputSlice(n node) ([]CommandOutput, error) {
 func (l *loader) MappingToCommandInputSlice(n node) ([]CommandInput, error) {
 	var inputs []CommandInput
 
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		i := CommandInput{}
@@ -120,7 +124,11 @@ func (l *loader) MappingToCommandInputSlice(n node) ([]CommandInput, error) {
 func (l *loader) MappingToCommandOutputSlice(n node) ([]CommandOutput, error) {
 	var outputs []CommandOutput
 
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		o := CommandOutput{}
@@ -133,3 +141,38 @@ func (l *loader) MappingToCommandOutputSlice(n node) ([]CommandOutput, error) {
 
 	return outputs, nil
 }
+
+func (l *loader) MappingToTool(n node) (Tool, error) {
+	t := Tool{}
+	if err := l.loadMappingToStruct(n, &t); err != nil {
+		return t, err
+	}
+	err := expandStdinInput(&t)
+	return t, err
+}
+
+// expandStdinInput expands the "stdin" input type shortcut.
+//
+// cwl spec (v1.1):
+// "type: stdin" on an input is equivalent to "type: File", "streamable: true"
+// and "stdin: $(inputs.an_input_name.path)" on the tool.
+func expandStdinInput(t *Tool) error {
+	for i, in := range t.Inputs {
+		if len(in.Type) != 1 {
+			continue
+		}
+		if _, ok := in.Type[0].(Stdin); !ok {
+			continue
+		}
+		if t.Stdin != "" {
+			return errf(`input %q has type "stdin", but the tool also sets stdin`, in.ID)
+		}
+		if in.InputBinding != nil {
+			return errf(`input %q has type "stdin" and can't have an inputBinding`, in.ID)
+		}
+		t.Inputs[i].Type = []InputType{FileType{}}
+		t.Inputs[i].Streamable = true
+		t.Stdin = Expression("$(inputs." + in.ID + ".path)")
+	}
+	return nil
+}
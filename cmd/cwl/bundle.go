@@ -0,0 +1,76 @@
+package main
+
+import (
+  "os"
+  "path/filepath"
+  "strings"
+  "github.com/buchanae/cwl"
+  "github.com/spf13/cobra"
+)
+
+type bundleOpts struct {
+  out string
+}
+
+func init() {
+  opts := bundleOpts{
+    out: "bundle.tar.gz",
+  }
+
+  cmd := &cobra.Command{
+    Use: "bundle <doc.cwl> [inputs.json]",
+    Short: "Write a document, its inputs and their files to an archive",
+    Long: `Write a document, the documents it references, its inputs and their files,
+including the files of default values, to a gzipped tar archive.
+
+The archive is run with "cwl run <bundle.tar.gz>".`,
+    Args: cobra.RangeArgs(1, 2),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      inputs := ""
+      if len(args) == 2 {
+        inputs = args[1]
+      }
+      return bundle(opts, args[0], inputs)
+    },
+  }
+  root.AddCommand(cmd)
+  cmd.Flags().StringVarP(&opts.out, "out", "o", opts.out, "path to write the archive to")
+}
+
+func bundle(opts bundleOpts, path, inputsPath string) error {
+  r, err := resolver()
+  if err != nil {
+    return err
+  }
+  doc, err := load(path)
+  if err != nil {
+    return err
+  }
+
+  var vals cwl.Values
+  if inputsPath != "" {
+    vals, err = cwl.LoadValuesFile(inputsPath)
+    if err != nil {
+      return err
+    }
+  }
+
+  f, err := os.Create(opts.out)
+  if err != nil {
+    return err
+  }
+  err = cwl.Bundle(f, doc, vals, filepath.Dir(inputsPath), r)
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    os.Remove(opts.out)
+    return err
+  }
+  return nil
+}
+
+// isBundle returns true if the path is an archive written by "cwl bundle".
+func isBundle(path string) bool {
+  return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
@@ -3,7 +3,6 @@ package main
 import (
   "fmt"
   "encoding/json"
-  "github.com/go-yaml/yaml"
   "github.com/buchanae/cwl"
   "github.com/spf13/cobra"
 )
@@ -37,9 +36,9 @@ func dump(opts dumpOpts, path string) error {
   var err error
 
   if opts.noResolve {
-    doc, err = cwl.LoadWithResolver(path, cwl.NoResolve())
+    doc, err = loadWith(path, cwl.NoResolve())
   } else {
-    doc, err = cwl.Load(path)
+    doc, err = load(path)
   }
   if err != nil {
     return err
@@ -59,7 +58,7 @@ func dump(opts dumpOpts, path string) error {
   if opts.json {
     b, err = json.MarshalIndent(doc, "", "  ")
   } else {
-    b, err = yaml.Marshal(doc)
+    b, err = cwl.MarshalYAML(doc)
   }
   if err != nil {
     return err
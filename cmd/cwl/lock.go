@@ -0,0 +1,58 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "sort"
+  "github.com/buchanae/cwl"
+  "github.com/spf13/cobra"
+)
+
+type lockOpts struct {
+  out string
+}
+
+func init() {
+  opts := lockOpts{
+    out: "cwl.lock",
+  }
+
+  cmd := &cobra.Command{
+    Use: "lock <doc.cwl>",
+    Short: "Record the digests of a document and the documents it references",
+    Long: `Record the sha256 digest of a document and every document it references,
+such as "run" documents and $imports fetched over HTTP, in a lockfile.
+
+Later loads fail if a document has changed, with --verify-lock.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return lock(opts, args[0])
+    },
+  }
+  root.AddCommand(cmd)
+  cmd.Flags().StringVarP(&opts.out, "out", "o", opts.out, "path to write the lockfile to")
+}
+
+func lock(opts lockOpts, path string) error {
+  r, err := resolver()
+  if err != nil {
+    return err
+  }
+  lf := &cwl.Lockfile{}
+  if _, err := cwl.LoadWithResolver(path, lf.Record(r)); err != nil {
+    return err
+  }
+  if err := lf.WriteFile(opts.out); err != nil {
+    return err
+  }
+
+  var locs []string
+  for loc := range lf.Documents {
+    locs = append(locs, loc)
+  }
+  sort.Strings(locs)
+  for _, loc := range locs {
+    fmt.Fprintf(os.Stderr, "%s %s\n", lf.Documents[loc], loc)
+  }
+  return nil
+}
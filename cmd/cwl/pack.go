@@ -0,0 +1,96 @@
+package main
+
+import (
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "sort"
+  "github.com/buchanae/cwl"
+  "github.com/spf13/cobra"
+)
+
+type unpackOpts struct {
+  outdir string
+}
+
+func init() {
+  cmd := &cobra.Command{
+    Use: "pack <doc.cwl>",
+    Short: "Pack a document and the documents it runs into one $graph document",
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return pack(args[0])
+    },
+  }
+  root.AddCommand(cmd)
+
+  opts := unpackOpts{
+    outdir: ".",
+  }
+  cmd = &cobra.Command{
+    Use: "unpack <packed.cwl>",
+    Short: "Split a $graph document into a file per document",
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return unpack(opts, args[0])
+    },
+  }
+  root.AddCommand(cmd)
+  cmd.Flags().StringVar(&opts.outdir, "outdir", opts.outdir, "directory to write the documents to")
+}
+
+func pack(path string) error {
+  doc, err := load(path)
+  if err != nil {
+    return err
+  }
+  g, err := cwl.Pack(doc)
+  if err != nil {
+    return err
+  }
+  b, err := cwl.MarshalYAML(g)
+  if err != nil {
+    return err
+  }
+  fmt.Print(string(b))
+  return nil
+}
+
+func unpack(opts unpackOpts, path string) error {
+  // Keep the references between graph entries.
+  doc, err := loadWith(path, cwl.NoResolve())
+  if err != nil {
+    return err
+  }
+  g, ok := doc.(cwl.Graph)
+  if !ok {
+    return errf("%s isn't a packed ($graph) document", path)
+  }
+
+  docs, err := cwl.Unpack(g)
+  if err != nil {
+    return err
+  }
+  var names []string
+  for name := range docs {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  if err := os.MkdirAll(opts.outdir, 0755); err != nil {
+    return err
+  }
+  for _, name := range names {
+    b, err := cwl.MarshalYAML(docs[name])
+    if err != nil {
+      return err
+    }
+    p := filepath.Join(opts.outdir, name)
+    if err := ioutil.WriteFile(p, b, 0644); err != nil {
+      return err
+    }
+    fmt.Println(p)
+  }
+  return nil
+}
@@ -2,6 +2,8 @@ package main
 
 import (
   "os"
+  "path/filepath"
+  "github.com/buchanae/cwl"
   "github.com/spf13/cobra"
 )
 
@@ -10,6 +12,77 @@ var root = cobra.Command{
 	SilenceUsage:  true,
 }
 
+type resolveOpts struct {
+  // cacheDir caches documents fetched over HTTP. Empty disables the cache.
+  cacheDir string
+  // offline resolves HTTP documents only from the cache.
+  offline bool
+  // verifyLock is a lockfile, written by "cwl lock", which
+  // the digests of the loaded documents are verified against.
+  verifyLock string
+}
+
+var resolveFlags = resolveOpts{
+  cacheDir: defaultCacheDir(),
+}
+
+func init() {
+  f := root.PersistentFlags()
+  f.StringVar(&resolveFlags.cacheDir, "cache-dir", resolveFlags.cacheDir,
+    "directory to cache documents fetched over HTTP in. Empty disables the cache")
+  f.BoolVar(&resolveFlags.offline, "offline", resolveFlags.offline,
+    "resolve HTTP documents only from the cache, without network access")
+  f.StringVar(&resolveFlags.verifyLock, "verify-lock", resolveFlags.verifyLock,
+    "fail if a loaded document doesn't match its digest in this lockfile, written by \"cwl lock\"")
+}
+
+func defaultCacheDir() string {
+  dir, err := os.UserCacheDir()
+  if err != nil {
+    return ""
+  }
+  return filepath.Join(dir, "cwl")
+}
+
+// resolver returns the resolver used to load documents,
+// configured by the global flags.
+func resolver() (cwl.Resolver, error) {
+  if resolveFlags.cacheDir == "" {
+    if resolveFlags.offline {
+      return nil, errf("--offline needs a --cache-dir")
+    }
+    return cwl.DefaultResolver{}, nil
+  }
+  return &cwl.CachingResolver{
+    Dir: resolveFlags.cacheDir,
+    Offline: resolveFlags.offline,
+  }, nil
+}
+
+// load loads a document with the resolver configured by the global flags.
+func load(path string) (cwl.Document, error) {
+  r, err := resolver()
+  if err != nil {
+    return nil, err
+  }
+  return loadWith(path, r)
+}
+
+// loadWith loads a document with "r", such as cwl.NoResolve(),
+// verifying the documents it reads against --verify-lock.
+func loadWith(path string, r cwl.Resolver) (cwl.Document, error) {
+  opts := cwl.LoadOptions{Resolver: r}
+  if resolveFlags.verifyLock != "" {
+    lf, err := cwl.LoadLockfile(resolveFlags.verifyLock)
+    if err != nil {
+      return nil, err
+    }
+    opts.Lock = lf
+  }
+  doc, _, err := cwl.LoadWithOptions(path, opts)
+  return doc, err
+}
+
 func main() {
   if err := root.Execute(); err != nil {
     os.Exit(1)
@@ -4,9 +4,14 @@ import (
   "context"
   "fmt"
   "encoding/json"
+  "io/ioutil"
+  "os"
   "path/filepath"
+  "strings"
+  "time"
   "github.com/buchanae/cwl"
   "github.com/buchanae/cwl/process"
+  "github.com/buchanae/cwl/process/exec/simple"
   localfs "github.com/buchanae/cwl/process/fs/local"
   //gsfs "github.com/buchanae/cwl/process/fs/gs"
 
@@ -19,37 +24,102 @@ import (
   "github.com/rs/xid"
 )
 
+type runOpts struct {
+  outdir string
+  debug bool
+  // local runs tools as local processes instead of in docker.
+  local bool
+  // stream enables streaming of streamable files, see process.StreamLinks.
+  stream bool
+  // timeout is the time limit of tools without a ToolTimeLimit requirement.
+  timeout time.Duration
+}
+
 func init() {
-  outdir := "cwl-output"
-  debug := false
+  opts := runOpts{
+    outdir: "cwl-output",
+  }
 
   cmd := &cobra.Command{
-    Use: "run <doc.cwl> <inputs.json>",
-    Args: cobra.ExactArgs(2),
+    Use: "run <doc.cwl[#id]> <inputs.json> | <bundle.tar.gz>",
+    Args: cobra.RangeArgs(1, 2),
     RunE: func(cmd *cobra.Command, args []string) error {
-      return run(args[0], args[1], outdir, debug)
+      if isBundle(args[0]) {
+        if len(args) != 1 {
+          return errf("a bundle includes its inputs")
+        }
+        return runBundle(args[0], opts)
+      }
+      if len(args) != 2 {
+        return errf("expected a document and an inputs file")
+      }
+      return run(args[0], args[1], opts)
     },
   }
   root.AddCommand(cmd)
   f := cmd.Flags()
 
-  f.StringVar(&outdir, "outdir", outdir, "")
-  f.BoolVar(&debug, "debug", debug, "")
+  f.StringVar(&opts.outdir, "outdir", opts.outdir, "")
+  f.BoolVar(&opts.debug, "debug", opts.debug, "")
+  f.BoolVar(&opts.local, "local", opts.local, "run tools as local processes instead of in docker")
+  f.BoolVar(&opts.stream, "stream", opts.stream,
+    "with --local, stream streamable files through named pipes")
+  f.DurationVar(&opts.timeout, "timeout", opts.timeout,
+    "default time limit of each tool, e.g. 2h. ToolTimeLimit takes precedence. 0 means no limit")
 }
 
-func run(path, inputsPath, outdir string, debug bool) error {
+func run(path, inputsPath string, opts runOpts) error {
   vals, err := cwl.LoadValuesFile(inputsPath)
   if err != nil {
     return err
   }
   inputsDir := filepath.Dir(inputsPath)
 
-  doc, err := cwl.Load(path)
+  doc, err := load(path)
+  if err != nil {
+    return err
+  }
+  return runValues(doc, vals, inputsDir, opts)
+}
+
+// runBundle runs an archive written by "cwl bundle". The documents are
+// loaded from the archive, and its files are extracted to a temporary
+// directory, which the locations of the inputs are relative to.
+func runBundle(path string, opts runOpts) error {
+  // The documents of a bundle are packed, so nothing is
+  // fetched or cached, and a lockfile doesn't match them.
+  for _, name := range []string{"verify-lock", "offline", "cache-dir"} {
+    if root.PersistentFlags().Changed(name) {
+      return errf("--%s can't be used to run a bundle", name)
+    }
+  }
+
+  a, err := cwl.OpenArchive(path)
+  if err != nil {
+    return err
+  }
+  doc, err := cwl.LoadWithResolver(cwl.BundleDocument, a)
+  if err != nil {
+    return err
+  }
+  vals, err := a.Job()
+  if err != nil {
+    return err
+  }
+
+  dir, err := ioutil.TempDir("", "cwl-bundle-")
   if err != nil {
     return err
   }
+  defer os.RemoveAll(dir)
+  if err := a.Extract(dir); err != nil {
+    return err
+  }
+  return runValues(doc, vals, dir, opts)
+}
 
-  r := runner{inputsDir, outdir, debug}
+func runValues(doc cwl.Document, vals cwl.Values, inputsDir string, opts runOpts) error {
+  r := runner{inputsDir, opts}
 
   outvals, err := r.runDoc(doc, vals)
   if err != nil {
@@ -67,24 +137,54 @@ func run(path, inputsPath, outdir string, debug bool) error {
 
 type runner struct {
   inputsDir string
-  outdir string
-  debug bool
+  opts runOpts
 }
 
 func (r *runner) runDoc(doc cwl.Document, vals cwl.Values) (cwl.Values, error) {
   switch z := doc.(type) {
-  case *cwl.Tool:
-    return r.runTool(z, vals)
   case *cwl.Workflow:
     return r.runWorkflow(z, vals)
+  case cwl.Graph:
+    ids := z.IDs()
+    if len(ids) == 0 {
+      return nil, errf("packed document has no entries with an id")
+    }
+    return nil, errf("packed document has no #main, select one of: %s (e.g. doc.cwl#%s)",
+      strings.Join(ids, ", "), ids[0])
+  default:
+    return r.executor().Execute(doc, vals)
+  }
+}
+
+// executor returns the process.Executor which runs tools.
+func (r *runner) executor() process.Executor {
+  if r.opts.local {
+    return &simple.Executor{
+      Outdir: r.opts.outdir,
+      InputsDir: r.inputsDir,
+      StreamInputs: r.opts.stream,
+      Timeout: r.opts.timeout,
+    }
+  }
+  return r
+}
+
+// Execute runs a tool in docker, via tugboat.
+func (r *runner) Execute(doc cwl.Document, vals cwl.Values) (cwl.Values, error) {
+  switch z := doc.(type) {
+  case *cwl.Tool:
+    return r.runTool(z, vals)
   default:
     return nil, fmt.Errorf(`running doc: unknown doc type "%s"`, doc.Doctype())
   }
 }
 
 func (r *runner) runWorkflow(wf *cwl.Workflow, vals cwl.Values) (cwl.Values, error) {
-  process.DebugWorkflow(wf, vals)
-  return nil, nil
+  wr := process.WorkflowRunner{
+    Executor: r.executor(),
+    Stream: r.opts.stream,
+  }
+  return wr.Run(wf, vals)
 }
 
 func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
@@ -138,18 +238,21 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
     }
   }
 
+  // TODO tugboat has no support for envExpr.
+  env, _ := proc.Env()
+
   task := &tug.Task{
     ID: "cwl-test1-" + xid.New().String(),
     ContainerImage: image,
     Command: cmd,
     Workdir: workdir,
     Volumes: []string{workdir, "/tmp"},
-    Env: proc.Env(),
+    Env: env,
 
     /* TODO need process.OutputBindings() */
     Outputs: []tug.File{
       {
-        URL: r.outdir,
+        URL: r.opts.outdir,
         Path: workdir,
       },
     },
@@ -172,6 +275,21 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
       files = append(files, flattenFiles(f)...)
     }
   }
+
+  // The stdin file is staged at the same path as in the host,
+  // like the other input files.
+  if stdin := proc.Stdin(); stdin != "" {
+    task.Stdin = stdin
+    staged := false
+    for _, f := range files {
+      if f.Path == stdin {
+        staged = true
+      }
+    }
+    if !staged {
+      files = append(files, cwl.File{Location: stdin, Path: stdin})
+    }
+  }
   for _, f := range files {
     task.Inputs = append(task.Inputs, tug.File{
       URL: f.Location,
@@ -181,10 +299,20 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
   }
 
   ctx := context.Background()
+  limit := r.opts.timeout
+  if l, ok := proc.TimeLimit(); ok {
+    limit = l
+  }
+  if limit > 0 {
+    var cancel context.CancelFunc
+    ctx, cancel = context.WithTimeout(ctx, limit)
+    defer cancel()
+  }
+
   store, _ := local.NewLocal()
   //store, _ := gsstore.NewGS("buchanae-funnel")
   var log tug.Logger
-  if r.debug {
+  if r.opts.debug {
     log = tug.StderrLogger{}
   } else {
     log = tug.EmptyLogger{}
@@ -198,10 +326,14 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
   if err != nil {
     panic(err)
   }
-  stage.LeaveDir = r.debug
+  stage.LeaveDir = r.opts.debug
   defer stage.RemoveAll()
 
+  // Canceling the context kills the container, and everything in it.
   err = tug.Run(ctx, task, stage, log, store, exec)
+  if err != nil && ctx.Err() == context.DeadlineExceeded {
+    return nil, &process.TimeoutError{Limit: limit}
+  }
   if err != nil {
     if e, ok := err.(*tug.ExecError); ok {
       for _, code := range tool.SuccessCodes {
@@ -217,7 +349,7 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
 
   //fmt.Println(strings.Join(cmd, " "))
 
-  outfs := localfs.NewLocal(r.outdir)
+  outfs := localfs.NewLocal(r.opts.outdir)
   outfs.CalcChecksum = true
   //outfs, err := gsfs.NewGS("buchanae-cwl-output")
   if err != nil {
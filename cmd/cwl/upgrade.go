@@ -0,0 +1,93 @@
+package main
+
+import (
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "github.com/buchanae/cwl"
+  "github.com/spf13/cobra"
+)
+
+type upgradeOpts struct {
+  to string
+  inPlace bool
+}
+
+func init() {
+  opts := upgradeOpts{
+    to: cwl.LatestVersion,
+  }
+
+  cmd := &cobra.Command{
+    Use: "upgrade <doc.cwl>",
+    Short: "Upgrade a document to a later version of CWL",
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return upgrade(opts, args[0])
+    },
+  }
+  root.AddCommand(cmd)
+
+  f := cmd.Flags()
+  f.StringVar(&opts.to, "to", opts.to, "version of CWL to upgrade to")
+  f.BoolVar(&opts.inPlace, "in-place", opts.inPlace,
+    "overwrite the document instead of writing it to stdout")
+}
+
+// upgrade writes the upgraded document as YAML, and a summary
+// of the changes to stderr. Referenced documents are left as they are.
+func upgrade(opts upgradeOpts, path string) error {
+  // Directives aren't resolved, and would be lost by writing the document back.
+  src, err := ioutil.ReadFile(path)
+  if err != nil {
+    return err
+  }
+  if err := cwl.CheckDirectives(src, path); err != nil {
+    return errf("can't upgrade a document with directives: %s", err)
+  }
+
+  doc, err := loadWith(path, cwl.NoResolve())
+  if err != nil {
+    return err
+  }
+
+  doc, changes, err := cwl.Upgrade(doc, opts.to)
+  if err != nil {
+    return err
+  }
+
+  b, err := cwl.MarshalYAML(doc)
+  if err != nil {
+    return err
+  }
+
+  // Check the result reloads, before overwriting anything.
+  _, _, err = cwl.LoadDocumentBytesWithOptions(b, filepath.Dir(path), cwl.LoadOptions{
+    Resolver: cwl.NoResolve(),
+  })
+  if err != nil {
+    return errf("upgraded document doesn't load: %s", err)
+  }
+
+  if opts.inPlace {
+    info, err := os.Stat(path)
+    if err != nil {
+      return err
+    }
+    err = ioutil.WriteFile(path, b, info.Mode())
+    if err != nil {
+      return err
+    }
+  } else {
+    fmt.Print(string(b))
+  }
+
+  if len(changes) == 0 {
+    fmt.Fprintf(os.Stderr, "%s: no changes\n", path)
+  }
+  for _, c := range changes {
+    fmt.Fprintf(os.Stderr, "%s: %s\n", path, c)
+  }
+  return nil
+}
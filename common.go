@@ -24,6 +24,34 @@ const (
 	MergeFlattened                 = "merge_flattened"
 )
 
+// PickValueMethod selects a value from the sources of a
+// step input or workflow output, added in v1.2.
+type PickValueMethod string
+
+const (
+	FirstNonNull   PickValueMethod = "first_non_null"
+	TheOnlyNonNull PickValueMethod = "the_only_non_null"
+	AllNonNull     PickValueMethod = "all_non_null"
+)
+
+// LoadListingMethod describes how much of a directory's listing
+// is loaded, added in v1.1.
+type LoadListingMethod string
+
+const (
+	NoListing      LoadListingMethod = "no_listing"
+	ShallowListing LoadListingMethod = "shallow_listing"
+	DeepListing    LoadListingMethod = "deep_listing"
+)
+
+// SecondaryFile describes a file which accompanies a primary file.
+// v1.0 only has the pattern, written as a string. Since v1.1,
+// Required is a boolean or an expression.
+type SecondaryFile struct {
+	Pattern  Expression `json:"pattern"`
+	Required Expression `json:"required,omitempty"`
+}
+
 type DocumentRef struct {
 	Location string
 }
@@ -55,6 +83,7 @@ type FileType struct{}
 type DirectoryType struct{}
 type Stderr struct{}
 type Stdout struct{}
+type Stdin struct{}
 
 type FileDir interface {
 	filedir()
@@ -96,6 +125,7 @@ func (FileType) String() string      { return "File" }
 func (DirectoryType) String() string { return "Directory" }
 func (Stderr) String() string        { return "stderr" }
 func (Stdout) String() string        { return "stdout" }
+func (Stdin) String() string         { return "stdin" }
 func (InputRecord) String() string   { return "record" }
 func (InputEnum) String() string     { return "enum" }
 func (InputArray) String() string    { return "array" }
@@ -115,6 +145,7 @@ func (FileType) MarshalText() ([]byte, error)      { return []byte("File"), nil
 func (DirectoryType) MarshalText() ([]byte, error) { return []byte("Directory"), nil }
 func (Stderr) MarshalText() ([]byte, error)        { return []byte("stderr"), nil }
 func (Stdout) MarshalText() ([]byte, error)        { return []byte("stdout"), nil }
+func (Stdin) MarshalText() ([]byte, error)         { return []byte("stdin"), nil }
 
 type Document interface {
 	Doctype() string
@@ -123,12 +154,15 @@ type Document interface {
 type Graph struct {
 	CWLVersion string `json:"cwlVersion,omitempty"`
   Docs []Document `json:"$graph"`
+
+	Extensions Extensions `json:"-"`
 }
 
 func (Tool) Doctype()       string    { return "CommandLineTool" }
 func (Script) Doctype()		string 	  { return "ScriptTool" }
 func (Workflow) Doctype() string      { return "Workflow" }
 func (ExpressionTool) Doctype() string { return "ExpressionTool" }
+func (Operation) Doctype() string      { return "Operation" }
 func (DocumentRef) Doctype() string   { return "DocumentRef" }
 func (Graph) Doctype() string { return "$graph" }
 
@@ -148,6 +182,7 @@ func (Double) inputtype()        {}
 func (String) inputtype()        {}
 func (FileType) inputtype()      {}
 func (DirectoryType) inputtype() {}
+func (Stdin) inputtype()         {}
 func (InputRecord) inputtype()   {}
 func (InputEnum) inputtype()     {}
 func (InputArray) inputtype()    {}
@@ -192,6 +227,7 @@ func (FileType) cwltype()      {}
 func (DirectoryType) cwltype() {}
 func (Stderr) cwltype()        {}
 func (Stdout) cwltype()        {}
+func (Stdin) cwltype()         {}
 func (InputRecord) cwltype()   {}
 func (InputEnum) cwltype()     {}
 func (InputArray) cwltype()    {}
@@ -219,6 +255,11 @@ func (SubworkflowFeatureRequirement) requirement()   {}
 func (ScatterFeatureRequirement) requirement()       {}
 func (MultipleInputFeatureRequirement) requirement() {}
 func (StepInputExpressionRequirement) requirement()  {}
+func (ToolTimeLimit) requirement()                   {}
+func (LoadListingRequirement) requirement()          {}
+func (NetworkAccess) requirement()                   {}
+func (WorkReuse) requirement()                       {}
+func (InplaceUpdateRequirement) requirement()        {}
 //func (PreCMDRequirement) requirement()               {}
 //func (PostCMDRequirement) requirement()               {}
 func (LRMRequirement) requirement()            	   {}
@@ -250,6 +291,15 @@ func (o *OptOut) Set(v bool) {
 	o.v = v
 }
 
+// ptr returns nil if the flag isn't set, for marshaling.
+func (o OptOut) ptr() *bool {
+	if !o.set {
+		return nil
+	}
+	v := o.v
+	return &v
+}
+
 func (o *OptOut) MarshalText() ([]byte, error) {
 	return []byte(fmt.Sprintf("%t", o.Value())), nil
 }
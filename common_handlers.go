@@ -22,6 +22,9 @@ func (l *loader) MappingToDocument(n node) (Document, error) {
   }
 
 	class := findKey(n, "class")
+	if err := l.checkClassVersion(n, class); err != nil {
+		return nil, err
+	}
 	switch strings.ToLower(class) {
 
 	case "commandlinetool":
@@ -52,6 +55,13 @@ func (l *loader) MappingToDocument(n node) (Document, error) {
 		}
 		return t, nil
 
+	case "operation":
+		op := &Operation{}
+		if err := l.load(n, op); err != nil {
+			return nil, err
+		}
+		return op, nil
+
 	default:
 		return nil, fmt.Errorf("unknown document class: '%s'", class)
 	}
@@ -61,18 +71,36 @@ func (l *loader) ScalarToDocument(n node) (Document, error) {
 	if _, ok := l.resolver.(noResolver); ok {
 		return DocumentRef{Location: n.Value}, nil
 	}
-	b, base, err := l.resolver.Resolve(l.base, n.Value)
+	// "#echo" refers to another document in the same $graph.
+	if strings.HasPrefix(n.Value, "#") {
+		if l.graph == nil {
+			return nil, l.errorAt(n, "run %q refers to a $graph document, but the document isn't packed", n.Value)
+		}
+		return l.loadEntry(n, n.Value)
+	}
+
+	// Relative to the file this node came from, which may be imported.
+	// "packed.cwl#main" loads one document of a packed file.
+	loc, entry := splitEntry(n.Value)
+	outer := l.baseOf(n)
+	file := docLocation(outer, loc)
+	if err := l.checkCycle(n, "run", file); err != nil {
+		return nil, err
+	}
+
+	b, base, err := l.resolver.Resolve(outer, loc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve document: %s", err)
 	}
-	doc, err := LoadDocumentBytes(b, base, l.resolver)
-	loc := l.base + "/" + n.Value
-	switch z := doc.(type) {
-	case *Tool:
-		z.SourceFile = loc
-	case *Workflow:
-	  	z.SourceFile = loc
+	o := loadOpts{
+		resolver: l.resolver,
+		strict:   l.strict,
+		warnings: l.warnings,
+		parents:  l.stack,
+		entry:    entry,
 	}
+	doc, err := loadDocumentBytes(b, base, file, o)
+	setSourceFile(doc, file)
 	return doc, err
 }
 
@@ -82,7 +110,11 @@ func (l *loader) ScalarToExpressionSlice(n node) ([]Expression, error) {
 
 func (l *loader) MappingToExpressionMap(n node) (map[string]Expression, error) {
 	out := map[string]Expression{}
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		expr := Expression("")
@@ -115,7 +147,7 @@ func (l *loader) SeqToExpressionMap(n node) (map[string]Expression, error) {
 }
 
 func (l *loader) SeqToStringSlice(n node) ([]string, error) {
-	strs := []string{}
+	var strs []string
 	for _, c := range n.Children {
 		strs = append(strs, c.Value)
 	}
@@ -156,7 +188,11 @@ func (l *loader) SeqToString(n node) (string, error) {
 func (l *loader) MappingToInputFieldSlice(n node) ([]InputField, error) {
 	var fields []InputField
 
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		i := InputField{Name: k}
@@ -331,6 +367,12 @@ func (l *loader) scalarToType(name string, isInput bool) cwltype {
 		t = Stdout{}
 	case "stderr":
 		t = Stderr{}
+	case "stdin":
+		// "stdin" is only valid as an input type.
+		if !isInput {
+			return nil
+		}
+		t = Stdin{}
 	case "record":
 		if isInput {
 			t = InputRecord{}
@@ -391,6 +433,30 @@ func (l *loader) MappingToSchemaDef(n node) (SchemaDef, error) {
 	}
 }
 
+// ScalarToSecondaryFile loads a secondary file pattern, as written in v1.0.
+func (l *loader) ScalarToSecondaryFile(n node) (SecondaryFile, error) {
+	return SecondaryFile{Pattern: Expression(n.Value)}, nil
+}
+
+func (l *loader) MappingToSecondaryFile(n node) (SecondaryFile, error) {
+	sf := SecondaryFile{}
+	if err := l.checkVersion(n, "v1.1", "secondaryFiles with a pattern and required"); err != nil {
+		return sf, err
+	}
+	err := l.loadMappingToStruct(n, &sf)
+	return sf, err
+}
+
+func (l *loader) ScalarToSecondaryFileSlice(n node) ([]SecondaryFile, error) {
+	sf, err := l.ScalarToSecondaryFile(n)
+	return []SecondaryFile{sf}, err
+}
+
+func (l *loader) MappingToSecondaryFileSlice(n node) ([]SecondaryFile, error) {
+	sf, err := l.MappingToSecondaryFile(n)
+	return []SecondaryFile{sf}, err
+}
+
 /* These are here to avoid the automatic loading of slice types in the loader */
 
 func (l *loader) SeqToInputTypeSlice(n node) ([]InputType, error) {
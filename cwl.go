@@ -2,10 +2,32 @@ package cwl
 
 import (
 	"fmt"
-	"github.com/commondream/yamlast"
 	"io/ioutil"
+	"strings"
 )
 
+// LoadOptions configure how a document is loaded.
+type LoadOptions struct {
+	// Resolver resolves the document and the documents it references.
+	// If nil, references aren't resolved, as with NoResolve(), like
+	// LoadWithResolver and LoadDocumentBytes.
+	Resolver Resolver
+	// Strict fails loading on unknown fields and requirement classes,
+	// instead of returning them as warnings. Fields and classes with a
+	// prefix declared in "$namespaces" (e.g. "sbg:") are always allowed.
+	Strict bool
+	// Entry selects the document to load from a packed ($graph) document,
+	// such as "#main". It may also be given as the fragment of the location,
+	// e.g. "packed.cwl#main".
+	//
+	// If empty, "#main" is loaded if the graph has one, unless references
+	// aren't resolved (NoResolve), in which case the whole Graph is loaded.
+	Entry string
+	// Lock, if not nil, verifies the digest of every resolved document
+	// against the lockfile, failing on a mismatch. See Lockfile.Verify.
+	Lock *Lockfile
+}
+
 func Load(loc string) (Document, error) {
 	return LoadWithResolver(loc, DefaultResolver{})
 }
@@ -14,6 +36,21 @@ func LoadWithResolver(loc string, r Resolver) (Document, error) {
 	if r == nil {
 		r = NoResolve()
 	}
+	doc, _, err := LoadWithOptions(loc, LoadOptions{Resolver: r})
+	return doc, err
+}
+
+// LoadWithOptions loads the document at "loc". Problems which don't stop
+// the document from loading, such as unknown fields, are returned as warnings.
+func LoadWithOptions(loc string, opts LoadOptions) (Document, []*LoadError, error) {
+	r := opts.Resolver
+	if r == nil {
+		r = NoResolve()
+	}
+	loc, entry := splitEntry(loc)
+	if opts.Entry != "" {
+		entry = opts.Entry
+	}
 
 	var b []byte
 	var base string
@@ -22,59 +59,165 @@ func LoadWithResolver(loc string, r Resolver) (Document, error) {
 	// If NoResolve() is being used, load the document bytes using
 	// the default resolver, but then continue with NoResolve().
 	if _, ok := r.(noResolver); ok {
-		d := DefaultResolver{}
+		var d Resolver = DefaultResolver{}
+		if opts.Lock != nil {
+			d = opts.Lock.Verify(d)
+		}
 		b, base, err = d.Resolve("", loc)
 	} else {
+		if opts.Lock != nil {
+			r = opts.Lock.Verify(r)
+		}
 		b, base, err = r.Resolve("", loc)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve document: %s", err)
-	}
-	doc, err := LoadDocumentBytes(b, base, r)
-	switch z := doc.(type) {
-	case *Tool:
-		z.SourceFile = loc
-	case *Workflow:
-	  	z.SourceFile = loc
+		return nil, nil, &LoadError{
+			File: loc,
+			Err:  fmt.Errorf("failed to resolve document: %s", err),
+		}
 	}
-	return doc, err
+
+	var warnings []*LoadError
+	o := loadOpts{resolver: r, strict: opts.Strict, warnings: &warnings, entry: entry}
+	doc, err := loadDocumentBytes(b, base, loc, o)
+	setSourceFile(doc, loc)
+	return doc, warnings, err
 }
 
+// loadOpts are shared by the loaders of a document
+// and the documents it references.
+type loadOpts struct {
+	resolver Resolver
+	strict   bool
+	// warnings collects the warnings of every document, if not nil.
+	warnings *[]*LoadError
+	// parents are the locations of the documents which reference
+	// this one through "run", outermost first.
+	parents []string
+	// entry is the ID of the document to load from a $graph.
+	entry string
+}
+
+// LoadDocumentBytes loads a document from bytes. Relative references,
+// such as "run" and "$import", are resolved against "base".
+//
+// Errors are returned as a *LoadError.
 func LoadDocumentBytes(b []byte, base string, r Resolver) (Document, error) {
-	if r == nil {
-		r = NoResolve()
+	return loadDocumentBytes(b, base, "", loadOpts{resolver: r})
+}
+
+// LoadDocumentBytesWithOptions loads a document from bytes, like
+// LoadDocumentBytes, returning warnings like LoadWithOptions.
+func LoadDocumentBytesWithOptions(b []byte, base string, opts LoadOptions) (Document, []*LoadError, error) {
+	r := opts.Resolver
+	if _, ok := r.(noResolver); r != nil && !ok && opts.Lock != nil {
+		r = opts.Lock.Verify(r)
 	}
+	var warnings []*LoadError
+	o := loadOpts{resolver: r, strict: opts.Strict, warnings: &warnings, entry: opts.Entry}
+	doc, err := loadDocumentBytes(b, base, "", o)
+	return doc, warnings, err
+}
 
-	l := loader{base, r}
+// loadDocumentBytes loads a document from bytes. "file" is the location
+// of the document, used in errors.
+func loadDocumentBytes(b []byte, base, file string, o loadOpts) (Document, error) {
 	// Parse the YAML into an AST
-	yamlnode, err := yamlast.Parse(b)
+	roots, err := parseYAML(b, file)
 	if err != nil {
-		return nil, fmt.Errorf("parsing yaml: %s", err)
+		return nil, err
 	}
 
-	if yamlnode == nil {
-		return nil, fmt.Errorf("empty yaml")
+	if len(roots) == 0 {
+		return nil, &LoadError{File: file, Err: fmt.Errorf("empty yaml")}
 	}
+	if len(roots) > 1 {
+		return nil, yamlError(file, roots[1],
+			"expected one YAML document, found %d. See LoadAllDocumentBytes()", len(roots))
+	}
+	return loadDocumentNode(roots[0], base, file, o)
+}
 
-	if len(yamlnode.Children) > 1 {
-		return nil, fmt.Errorf("unexpected child count")
+// LoadAllDocumentBytes loads every document in a multi-document
+// YAML stream, with documents separated by "---".
+//
+// Errors are returned as a *LoadError.
+func LoadAllDocumentBytes(b []byte, base string, r Resolver) ([]Document, error) {
+	roots, err := parseYAML(b, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, root := range roots {
+		d, err := loadDocumentNode(root, base, "", loadOpts{resolver: r})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+func loadDocumentNode(start node, base, file string, o loadOpts) (Document, error) {
+	if o.resolver == nil {
+		o.resolver = NoResolve()
+	}
+	l := loader{
+		base:     base,
+		resolver: o.resolver,
+		file:     file,
+		strict:   o.strict,
+		warnings: o.warnings,
+	}
+	l.stack = append(l.stack, o.parents...)
+	if file != "" {
+		l.stack = append(l.stack, docLocation("", file))
 	}
 
 	// Being recursively processing the tree.
 	var d Document
-	start := node(yamlnode.Children[0])
+	var err error
+	l.root = start
 	start, err = l.preprocess(start)
 	if err != nil {
+		return nil, l.wrapErr(l.root, err)
+	}
+	l.root = start
+	l.indexPositions()
+	l.namespaces = findNamespaces(start)
+	if err := l.checkDocVersion(start); err != nil {
 		return nil, err
 	}
 
+	// Packed documents load a single entry point,
+	// such as "#main", instead of the whole graph.
+	l.graph = graphEntries(start)
+	entry := o.entry
+	if _, ok := o.resolver.(noResolver); !ok && entry == "" && l.graph[mainEntry] != nil {
+		entry = mainEntry
+	}
+	if entry != "" {
+		if l.graph == nil {
+			if id := findKey(start, "id"); strings.TrimPrefix(id, "#") != strings.TrimPrefix(entry, "#") {
+				return nil, l.errorAt(start, "can't load %q, the document isn't packed ($graph)", entry)
+			}
+		} else {
+			d, err := l.loadEntry(start, entry)
+			if err != nil {
+				return nil, l.wrapErr(start, err)
+			}
+			return d, nil
+		}
+	}
+
 	// Dump the tree for debugging.
 	//dump(start, "")
 
 	err = l.load(start, &d)
 	if err != nil {
-		return nil, err
+		return nil, l.wrapErr(start, err)
 	}
 	if d != nil {
 		return d, nil
@@ -87,35 +230,85 @@ func LoadValuesFile(p string) (Values, error) {
 	if err != nil {
 		return nil, err
 	}
-	return LoadValuesBytes(b)
+	return loadValuesBytes(b, p)
 }
 
+// LoadValuesBytes loads input values from bytes.
+//
+// Errors are returned as a *LoadError.
 func LoadValuesBytes(b []byte) (Values, error) {
-	l := loader{}
+	return loadValuesBytes(b, "")
+}
+
+func loadValuesBytes(b []byte, file string) (Values, error) {
 	// Parse the YAML into an AST
-	yamlnode, err := yamlast.Parse(b)
+	roots, err := parseYAML(b, file)
 	if err != nil {
-		return nil, fmt.Errorf("parsing yaml: %s", err)
+		return nil, err
 	}
 
-	v := Values{}
-	if yamlnode == nil {
-		return v, nil
+	if len(roots) == 0 {
+		return Values{}, nil
+	}
+	if len(roots) > 1 {
+		return nil, yamlError(file, roots[1],
+			"expected one YAML document, found %d. See LoadAllValuesBytes()", len(roots))
+	}
+	return loadValuesNode(roots[0], file)
+}
+
+// LoadAllValuesFile loads input values from every document in
+// a multi-document YAML file, such as one job per sample.
+func LoadAllValuesFile(p string) ([]Values, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
 	}
+	return loadAllValuesBytes(b, p)
+}
+
+// LoadAllValuesBytes loads input values from every document
+// in a multi-document YAML stream.
+//
+// Errors are returned as a *LoadError.
+func LoadAllValuesBytes(b []byte) ([]Values, error) {
+	return loadAllValuesBytes(b, "")
+}
 
-	if len(yamlnode.Children) > 1 {
-		return nil, fmt.Errorf("unexpected child count")
+func loadAllValuesBytes(b []byte, file string) ([]Values, error) {
+	roots, err := parseYAML(b, file)
+	if err != nil {
+		return nil, err
 	}
 
-	start := node(yamlnode.Children[0])
+	var all []Values
+	for _, root := range roots {
+		v, err := loadValuesNode(root, file)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, v)
+	}
+	return all, nil
+}
+
+func loadValuesNode(start node, file string) (Values, error) {
+	// Input values don't reference other documents.
+	l := loader{file: file, resolver: NoResolve()}
+
+	var err error
+	v := Values{}
+	l.root = start
 	start, err = l.preprocess(start)
 	if err != nil {
-		return nil, err
+		return nil, l.wrapErr(l.root, err)
 	}
+	l.root = start
+	l.indexPositions()
 
 	err = l.load(start, &v)
 	if err != nil {
-		return nil, err
+		return nil, l.wrapErr(start, err)
 	}
 	return v, nil
 }
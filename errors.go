@@ -0,0 +1,358 @@
+package cwl
+
+import (
+	"fmt"
+	"github.com/commondream/yamlast"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LoadError is an error found while loading a CWL document,
+// with the position in the document where it was found.
+type LoadError struct {
+	// File is the location of the document, if known.
+	File string
+	// Line and Column are 1-based. Zero means the position is unknown.
+	Line, Column int
+	// Path is the path of fields leading to the error,
+	// e.g. "steps.align.in.reads".
+	Path string
+	// Err is the underlying error. For errors in a document referenced
+	// by "run" or "$import", Err is the *LoadError of that document.
+	Err error
+
+	// loader which created the error, used to avoid wrapping an error
+	// more than once while it bubbles up through nested load() calls.
+	src *loader
+}
+
+func (e *LoadError) Error() string {
+	var parts []string
+	pos := e.File
+	if e.Line > 0 {
+		pos += fmt.Sprintf(":%d", e.Line)
+		if e.Column > 0 {
+			pos += fmt.Sprintf(":%d", e.Column)
+		}
+		pos = strings.TrimPrefix(pos, ":")
+	}
+	if pos != "" {
+		parts = append(parts, pos)
+	}
+	if e.Path != "" {
+		parts = append(parts, e.Path)
+	}
+	parts = append(parts, e.Err.Error())
+	return strings.Join(parts, ": ")
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// errorAt returns a LoadError positioned at node "n".
+func (l *loader) errorAt(n node, msg string, args ...interface{}) error {
+	return l.wrapErr(n, errf(msg, args...))
+}
+
+// wrapErr wraps "err" in a LoadError positioned at node "n", unless
+// it has already been positioned by this loader. If "n" can't be found
+// in the document (e.g. it's a copy made while transforming a type),
+// the error is returned unchanged, to be positioned by the caller.
+func (l *loader) wrapErr(n node, err error) error {
+	if e, ok := err.(*LoadError); ok && e.src == l {
+		return err
+	}
+
+	o, path, ok := l.locate(n)
+	if !ok {
+		return err
+	}
+	return &LoadError{
+		File:   o.file,
+		Line:   n.Line + 1,
+		Column: n.Column + 1,
+		Path:   path,
+		Err:    err,
+		src:    l,
+	}
+}
+
+// origin describes the file a node was loaded from.
+type origin struct {
+	// file is the location of the file, used in errors.
+	file string
+	// base is used to resolve relative references in the file.
+	base string
+}
+
+// setOrigin records that the subtree rooted at "n" was loaded
+// from a different file than its parent, e.g. by "$import".
+func (l *loader) setOrigin(n node, file, base string) {
+	if l.origins == nil {
+		l.origins = map[*yamlast.Node]origin{}
+	}
+	l.origins[n] = origin{file, base}
+}
+
+// baseOf returns the base which relative references
+// in node "n" are resolved against.
+func (l *loader) baseOf(n node) string {
+	if o, _, ok := l.locate(n); ok {
+		return o.base
+	}
+	return l.base
+}
+
+// locate finds node "n" in the document, returning the file
+// it came from and its field path.
+func (l *loader) locate(n node) (origin, string, bool) {
+	if l.root == nil || n == nil {
+		return origin{}, "", false
+	}
+	if l.positions != nil && l.indexed == l.root {
+		if p, ok := l.positions[n]; ok {
+			return p.origin, p.path, true
+		}
+		if len(l.pending) == 0 {
+			return origin{}, "", false
+		}
+	}
+	var path []string
+	o, found := l.walkPath(l.root, n, origin{l.file, l.base}, &path)
+	if !found {
+		// The node may be in a document being imported. The path
+		// is then relative to the root of the imported document.
+		for i := len(l.pending) - 1; i >= 0 && !found; i-- {
+			path = nil
+			o, found = l.walkPath(l.pending[i], n, origin{l.file, l.base}, &path)
+		}
+	}
+	return o, strings.Join(path, "."), found
+}
+
+// position is the file a node came from and its field path.
+type position struct {
+	origin origin
+	path   string
+}
+
+// indexPositions records the position of every node of the document,
+// so that locating a node, e.g. for each warning, doesn't walk the whole
+// document again. It must be called once the tree is complete, after
+// preprocessing. Like walkPath, a node which appears more than once,
+// e.g. through a merge key, is positioned at its first appearance.
+func (l *loader) indexPositions() {
+	l.positions = map[*yamlast.Node]position{}
+	l.indexed = l.root
+	if l.root != nil {
+		l.indexNode(l.root, origin{l.file, l.base}, nil)
+	}
+}
+
+func (l *loader) indexNode(cur node, o origin, path []string) {
+	if x, ok := l.origins[cur]; ok {
+		o = x
+	}
+	if _, ok := l.positions[cur]; ok {
+		return
+	}
+	l.positions[cur] = position{o, strings.Join(path, ".")}
+
+	// The path is copied before appending, because it's shared by siblings.
+	path = path[:len(path):len(path)]
+	switch cur.Kind {
+	case yamlast.MappingNode:
+		for i := 0; i+1 < len(cur.Children); i += 2 {
+			k := cur.Children[i]
+			v := cur.Children[i+1]
+			p := append(path, k.Value)
+			if _, ok := l.positions[k]; !ok {
+				ko := o
+				if x, ok := l.origins[k]; ok {
+					ko = x
+				}
+				l.positions[k] = position{ko, strings.Join(p, ".")}
+			}
+			l.indexNode(v, o, p)
+		}
+
+	case yamlast.SequenceNode, yamlast.DocumentNode:
+		for i, c := range cur.Children {
+			l.indexNode(c, o, append(path, seqItemName(c, i)))
+		}
+	}
+}
+
+func (l *loader) walkPath(cur, n node, o origin, path *[]string) (origin, bool) {
+	if x, ok := l.origins[cur]; ok {
+		o = x
+	}
+	if cur == n {
+		return o, true
+	}
+
+	switch cur.Kind {
+	case yamlast.MappingNode:
+		for i := 0; i+1 < len(cur.Children); i += 2 {
+			k := cur.Children[i]
+			v := cur.Children[i+1]
+			*path = append(*path, k.Value)
+			if k == n {
+				if x, ok := l.origins[k]; ok {
+					o = x
+				}
+				return o, true
+			}
+			if x, ok := l.walkPath(v, n, o, path); ok {
+				return x, true
+			}
+			*path = (*path)[:len(*path)-1]
+		}
+
+	case yamlast.SequenceNode, yamlast.DocumentNode:
+		for i, c := range cur.Children {
+			*path = append(*path, seqItemName(c, i))
+			if x, ok := l.walkPath(c, n, o, path); ok {
+				return x, true
+			}
+			*path = (*path)[:len(*path)-1]
+		}
+	}
+	return origin{}, false
+}
+
+// seqItemName names an item of a sequence in a field path:
+// by its "id" field if it has one, otherwise by its index.
+func seqItemName(n node, i int) string {
+	if n.Kind == yamlast.MappingNode {
+		for j := 0; j+1 < len(n.Children); j += 2 {
+			k := n.Children[j]
+			v := n.Children[j+1]
+			if strings.ToLower(k.Value) == "id" && v.Kind == yamlast.ScalarNode {
+				return strings.TrimPrefix(v.Value, "#")
+			}
+		}
+	}
+	return strconv.Itoa(i)
+}
+
+var yamlLineRx = regexp.MustCompile(`line (\d+)`)
+
+// parseError wraps a YAML parser error in a LoadError,
+// picking the line number out of the message if possible.
+func parseError(file string, err error) error {
+	e := &LoadError{
+		File: file,
+		Err:  errf("parsing yaml: %s", err),
+	}
+	if m := yamlLineRx.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
+
+// docLocation returns the location of a document referenced by "loc",
+// relative to "base", for use in errors and to detect cycles.
+func docLocation(base, loc string) string {
+	if u, ok := isHTTP(base, loc); ok {
+		return u.String()
+	}
+	if gl, ok := isGit(base, loc); ok {
+		return gl.String()
+	}
+	if filepath.IsAbs(loc) || base == "" {
+		return filepath.Clean(loc)
+	}
+	return filepath.Join(base, loc)
+}
+
+// checkCycle returns an error if the document at "loc" is already
+// being loaded, e.g. a file which imports itself.
+func (l *loader) checkCycle(n node, directive, loc string) error {
+	for _, s := range l.stack {
+		if s == loc {
+			chain := append(append([]string{}, l.stack...), loc)
+			return l.errorAt(n, "%s cycle: %s", directive, strings.Join(chain, " -> "))
+		}
+	}
+	return nil
+}
+
+// warn reports a problem which doesn't stop the document from loading,
+// positioned at node "n". In strict mode, the warning is returned as an
+// error instead.
+func (l *loader) warn(n node, msg string, args ...interface{}) error {
+	err := l.wrapErr(n, errf(msg, args...))
+	e, ok := err.(*LoadError)
+	if !ok {
+		e = &LoadError{
+			File:   l.file,
+			Line:   n.Line + 1,
+			Column: n.Column + 1,
+			Err:    err,
+			src:    l,
+		}
+	}
+
+	if l.strict {
+		return e
+	}
+	if l.warnings != nil {
+		*l.warnings = append(*l.warnings, e)
+	}
+	return nil
+}
+
+var cwltypeType = reflect.TypeOf((*cwltype)(nil)).Elem()
+
+// unknownField reports a field, with key node "k", which doesn't match
+// any field of the struct type "typ" being loaded.
+func (l *loader) unknownField(k node, typ reflect.Type) error {
+	name := strings.ToLower(k.Value)
+	// "class" and the "type" of a schema, such as "type: array", are
+	// used to pick the type being loaded, and "$" fields are directives,
+	// such as "$namespaces".
+	if name == "class" || strings.HasPrefix(name, "$") {
+		return nil
+	}
+	if name == "type" && typ.Implements(cwltypeType) {
+		return nil
+	}
+	if l.isNamespaced(k.Value) {
+		return nil
+	}
+	return l.warn(k, "unknown field %q", k.Value)
+}
+
+// isNamespaced returns true if a name is an extension: a full URI,
+// or a name with a prefix declared in "$namespaces", e.g. "sbg:Hint".
+// Prefixes are case sensitive.
+func (l *loader) isNamespaced(name string) bool {
+	if strings.Contains(name, "://") {
+		return true
+	}
+	i := strings.Index(name, ":")
+	if i <= 0 {
+		return false
+	}
+	_, ok := l.namespaces[name[:i]]
+	return ok
+}
+
+// findNamespaces returns the prefixes declared in the
+// "$namespaces" field of a document.
+func findNamespaces(n node) map[string]string {
+	ns := map[string]string{}
+	v, ok := findValue(n, "$namespaces")
+	if !ok || v.Kind != yamlast.MappingNode {
+		return ns
+	}
+	for i := 0; i+1 < len(v.Children); i += 2 {
+		ns[v.Children[i].Value] = v.Children[i+1].Value
+	}
+	return ns
+}
@@ -0,0 +1,204 @@
+package cwl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadErrorPosition(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  - id: align
+    run: tool.cwl
+    in:
+      - id: reads
+        source: one
+        source: two
+    out: []
+`
+	_, err := LoadDocumentBytes([]byte(doc), ".", NoResolve())
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.Line != 12 || e.Column != 9 {
+		t.Errorf("unexpected position %d:%d", e.Line, e.Column)
+	}
+	if e.Path != "steps.align.in.reads.source" {
+		t.Errorf("unexpected path %q", e.Path)
+	}
+}
+
+func TestLoadErrorNested(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-errors-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wf := `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  align:
+    run: tool.cwl
+    in: []
+    out: []
+`
+	tool := `
+cwlVersion: v1.0
+class: CommandLineTool
+inputs:
+  reads:
+    - type: string
+    - foo: bar
+outputs: []
+`
+	ioutil.WriteFile(filepath.Join(dir, "wf.cwl"), []byte(wf), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "tool.cwl"), []byte(tool), 0644)
+
+	wfPath := filepath.Join(dir, "wf.cwl")
+	_, err = Load(wfPath)
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.File != wfPath || e.Line != 8 || e.Path != "steps.align.run" {
+		t.Errorf("unexpected outer error: %s", e)
+	}
+
+	nested, ok := e.Err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected nested *LoadError, got %T: %v", e.Err, e.Err)
+	}
+	if nested.File != filepath.Join(dir, "tool.cwl") || nested.Line != 6 || nested.Path != "inputs.reads" {
+		t.Errorf("unexpected nested error: %s", nested)
+	}
+}
+
+func TestLoadErrorYAML(t *testing.T) {
+	_, err := LoadValuesBytes([]byte("a: b\n  c: : d\n"))
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.Line != 2 {
+		t.Errorf("unexpected line %d in %s", e.Line, e)
+	}
+}
+
+func TestLoadWarnings(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: CommandLineTool
+$namespaces:
+  sbg: https://sevenbridges.com
+sbg:revision: 2
+inputs:
+  reads:
+    type: File
+    inputBindng:
+      position: 1
+outputs: []
+hints:
+  - class: DockerRequirment
+  - class: sbg:SaveLogs
+`
+	_, warnings, err := LoadDocumentBytesWithOptions([]byte(doc), ".", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if w := warnings[0]; w.Line != 10 || w.Path != "inputs.reads.inputBindng" {
+		t.Errorf("unexpected warning: %s", w)
+	}
+	if w := warnings[1]; w.Line != 14 || !strings.Contains(w.Error(), "DockerRequirment") {
+		t.Errorf("unexpected warning: %s", w)
+	}
+
+	_, _, err = LoadDocumentBytesWithOptions([]byte(doc), ".", LoadOptions{Strict: true})
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.Path != "inputs.reads.inputBindng" {
+		t.Errorf("unexpected error: %s", e)
+	}
+}
+
+func TestLoadOptionsNilResolver(t *testing.T) {
+	// A nil Resolver means references aren't resolved, for both loaders.
+	path := "examples/023-count-lines1-wf/tool.cwl"
+	d, _, err := LoadWithOptions(path, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := d.(*Workflow)
+	if _, ok := wf.Steps[0].Run.(DocumentRef); !ok {
+		t.Errorf("expected an unresolved reference, got %T", wf.Steps[0].Run)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _, err = LoadDocumentBytesWithOptions(b, path, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf = d.(*Workflow)
+	if _, ok := wf.Steps[0].Run.(DocumentRef); !ok {
+		t.Errorf("expected an unresolved reference, got %T", wf.Steps[0].Run)
+	}
+}
+
+func TestLoadWarningsNamespaceCase(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: CommandLineTool
+$namespaces:
+  SBG: https://sevenbridges.com
+SBG:revision: 2
+sbg:other: 3
+inputs: []
+outputs: []
+`
+	_, warnings, err := LoadDocumentBytesWithOptions([]byte(doc), ".", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "sbg:other") {
+		t.Errorf("expected a warning for the undeclared prefix only, got %v", warnings)
+	}
+}
+
+func TestLoadManyWarnings(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("cwlVersion: v1.0\nclass: CommandLineTool\ninputs: []\noutputs: []\n")
+	const n = 5000
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "unknown%d: %d\n", i, i)
+	}
+	_, warnings, err := LoadDocumentBytesWithOptions([]byte(b.String()), ".", LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != n {
+		t.Fatalf("expected %d warnings, got %d", n, len(warnings))
+	}
+	if w := warnings[n-1]; w.Line != n+4 || w.Path != fmt.Sprintf("unknown%d", n-1) {
+		t.Errorf("unexpected warning: %s", w)
+	}
+}
@@ -2,7 +2,6 @@ package expr
 
 import (
 	"fmt"
-	"cwl"
 	"github.com/kr/pretty"
 )
 
@@ -11,15 +10,6 @@ func errf(msg string, args ...interface{}) error {
 	return fmt.Errorf(msg, args...)
 }
 
-// getPos is a helper for accessing the Position field
-// of a possibly nil CommandLineBinding
-func getPos(in *cwl.CommandLineBinding) int {
-	if in == nil {
-		return 0
-	}
-	return in.Position
-}
-
 func debug(i ...interface{}) {
 	pretty.Println(i...)
 }
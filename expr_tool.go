@@ -4,9 +4,10 @@ type ExpressionTool struct {
 	CWLVersion string `json:"cwlVersion,omitempty"`
 	Class      string `json:"class,omitempty"`
 
-	ID    string `json:"id,omitempty"`
-	Label string `json:"label,omitempty"`
-	Doc   string `json:"doc,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Label      string `json:"label,omitempty"`
+	Doc        string `json:"doc,omitempty"`
+	SourceFile string `json:"-"`
 
 	Hints        []Requirement `json:"hints,omitempty"`
 	Requirements []Requirement `json:"requirements,omitempty"`
@@ -15,6 +16,8 @@ type ExpressionTool struct {
 	Outputs []CommandOutput `json:"outputs,omitempty"`
 
 	Expression Expression `json:"expression,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 /*
@@ -0,0 +1,156 @@
+package cwl
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/commondream/yamlast"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Extensions holds the fields of a document object which aren't part of
+// the CWL spec, such as "$namespaces", "$schemas" and namespaced fields
+// like "s:author", so that they are kept when the document is dumped.
+//
+// Values are plain YAML data: maps, lists, strings, numbers, bools and nil.
+type Extensions map[string]interface{}
+
+var extensionsType = reflect.TypeOf(Extensions{})
+
+// keepExtension stores an unknown field, with key "k" and value "v",
+// in the Extensions field of the struct "val", if it has one.
+func (l *loader) keepExtension(k, v node, val reflect.Value) error {
+	name := strings.ToLower(k.Value)
+	if name == "class" {
+		return nil
+	}
+	if name == "type" && val.Type().Implements(cwltypeType) {
+		return nil
+	}
+
+	f := val.FieldByName("Extensions")
+	if !f.IsValid() || f.Type() != extensionsType {
+		return nil
+	}
+	x, err := l.rawValue(v)
+	if err != nil {
+		return err
+	}
+	if f.IsNil() {
+		f.Set(reflect.ValueOf(Extensions{}))
+	}
+	f.SetMapIndex(reflect.ValueOf(k.Value), reflect.ValueOf(&x).Elem())
+	return nil
+}
+
+// rawValue converts a node to plain YAML data, without interpreting it as CWL.
+func (l *loader) rawValue(n node) (interface{}, error) {
+	switch n.Kind {
+	case yamlast.MappingNode:
+		m := map[string]interface{}{}
+		items, err := itermap(n)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range items {
+			x, err := l.rawValue(kv.v)
+			if err != nil {
+				return nil, err
+			}
+			m[kv.k] = x
+		}
+		return m, nil
+
+	case yamlast.SequenceNode:
+		s := []interface{}{}
+		for _, c := range n.Children {
+			x, err := l.rawValue(c)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, x)
+		}
+		return s, nil
+
+	case yamlast.ScalarNode:
+		return scalarValue(n), nil
+	}
+	return nil, l.errorAt(n, "unexpected YAML node kind")
+}
+
+// scalarValue resolves the type of a scalar, following the YAML core schema.
+// Quoted scalars are always strings. Infinity and NaN are kept as strings,
+// since they can't be marshaled to JSON.
+func scalarValue(n node) interface{} {
+	tag := n.Tag
+	if tag == "" && !n.Implicit {
+		return n.Value
+	}
+	v := n.Value
+
+	if tag == "" || tag == "!!null" {
+		switch v {
+		case "", "~", "null", "Null", "NULL":
+			return nil
+		}
+	}
+	if tag == "" || tag == "!!bool" {
+		switch v {
+		case "true", "True", "TRUE":
+			return true
+		case "false", "False", "FALSE":
+			return false
+		}
+	}
+	if tag == "" || tag == "!!int" {
+		if i, err := strconv.ParseInt(v, 0, 64); err == nil {
+			return i
+		}
+	}
+	if tag == "" || tag == "!!float" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f
+		}
+	}
+	return v
+}
+
+// marshalExtensions marshals "v", which must marshal to a JSON object,
+// adding the extension fields to the object.
+func marshalExtensions(v interface{}, ext Extensions) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(ext) == 0 {
+		return b, err
+	}
+	if len(b) < 2 || b[0] != '{' {
+		return nil, errf("can't add extension fields to %s", b)
+	}
+
+	var keys []string
+	for k := range ext {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(b[:len(b)-1])
+	for i, k := range keys {
+		kb, _ := json.Marshal(k)
+		vb, err := json.Marshal(ext[k])
+		if err != nil {
+			return nil, errf("marshaling extension %q: %s", k, err)
+		}
+		if i > 0 || len(b) > 2 {
+			buf.WriteByte(',')
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
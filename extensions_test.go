@@ -0,0 +1,93 @@
+package cwl
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtensions(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: CommandLineTool
+$namespaces:
+  s: https://schema.org/
+  arv: http://arvados.org/cwl#
+$schemas:
+  - https://schema.org/version/latest/schemaorg-current-http.rdf
+s:license: https://spdx.org/licenses/Apache-2.0
+s:author:
+  - class: s:Person
+    s:name: Jane Doe
+    s:age: 42
+    s:retired: false
+    s:zip: "02139"
+inputs:
+  reads:
+    type: File
+    s:format: fastq
+outputs: []
+requirements:
+  - class: DockerRequirement
+    dockerPull: alpine
+    arv:pull: always
+hints:
+  - class: arv:RuntimeConstraints
+    keep_cache: 512
+    outputDirType: keep_output_dir
+`
+	d, err := LoadDocumentBytes([]byte(doc), ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := d.(*Tool)
+
+	author := []interface{}{
+		map[string]interface{}{
+			"class":     "s:Person",
+			"s:name":    "Jane Doe",
+			"s:age":     int64(42),
+			"s:retired": false,
+			"s:zip":     "02139",
+		},
+	}
+	if !reflect.DeepEqual(tool.Extensions["s:author"], author) {
+		t.Errorf("unexpected s:author: %#v", tool.Extensions["s:author"])
+	}
+	if _, ok := tool.Extensions["$namespaces"]; !ok {
+		t.Errorf("expected $namespaces to be kept, got %#v", tool.Extensions)
+	}
+	if tool.Inputs[0].Extensions["s:format"] != "fastq" {
+		t.Errorf("unexpected input extensions: %#v", tool.Inputs[0].Extensions)
+	}
+	docker := tool.Requirements[0].(DockerRequirement)
+	if docker.Extensions["arv:pull"] != "always" {
+		t.Errorf("unexpected requirement extensions: %#v", docker.Extensions)
+	}
+	rc := tool.Hints[0].(UnknownRequirement)
+	if rc.Name != "arv:RuntimeConstraints" || rc.Extensions["keep_cache"] != int64(512) {
+		t.Errorf("unexpected unknown requirement: %#v", rc)
+	}
+
+	b, err := json.Marshal(tool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := LoadDocumentBytes(b, ".", nil)
+	if err != nil {
+		t.Fatalf("reloading %s: %s", b, err)
+	}
+	tool2 := d2.(*Tool)
+
+	// JSON doesn't tell ints from floats, so compare the marshaled documents.
+	b2, err := json.Marshal(tool2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(b2) {
+		t.Errorf("extensions didn't round trip:\n%s\n%s", b, b2)
+	}
+	if !reflect.DeepEqual(tool2.Extensions["s:author"], author) {
+		t.Errorf("unexpected s:author after round trip: %#v", tool2.Extensions["s:author"])
+	}
+}
@@ -0,0 +1,67 @@
+package cwl
+
+import (
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// FSResolver resolves documents from a file system, such as
+// an embed.FS of documents built into a binary. Locations are
+// slash-separated paths, relative to the referencing document,
+// or to the root of the file system if they start with "/".
+type FSResolver struct {
+	FS fs.FS
+}
+
+func (r FSResolver) Resolve(base, loc string) ([]byte, string, error) {
+	if u, ok := isHTTP(base, loc); ok {
+		return nil, "", errf("%s isn't in the file system", u)
+	}
+
+	p, err := fsPath(base, loc)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := fs.ReadFile(r.FS, p)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, path.Dir(p), nil
+}
+
+// fsPath returns the path of "loc" in a file system, relative to the
+// directory "base", or to the root if "loc" starts with "/".
+func fsPath(base, loc string) (string, error) {
+	p := loc
+	if strings.HasPrefix(p, "/") {
+		p = path.Clean(strings.TrimPrefix(p, "/"))
+	} else {
+		p = path.Join(base, p)
+	}
+	// fs.FS paths can't refer outside of the file system, e.g. "../x.cwl".
+	if !fs.ValidPath(p) {
+		return "", errf("%s is outside of the file system", loc)
+	}
+	return p, nil
+}
+
+// LoadFS loads the document at "name" from a file system, resolving
+// the documents it references from the same file system.
+func LoadFS(fsys fs.FS, name string) (Document, error) {
+	return LoadWithResolver(name, FSResolver{FS: fsys})
+}
+
+// LoadReader loads a document from a reader, like LoadDocumentBytes.
+// Relative references are resolved against "base" by "r".
+//
+// Errors are returned as a *LoadError.
+func LoadReader(rd io.Reader, base string, r Resolver) (Document, error) {
+	b, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return nil, &LoadError{Err: errf("failed to read document: %s", err)}
+	}
+	return LoadDocumentBytes(b, base, r)
+}
@@ -0,0 +1,92 @@
+package cwl
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"wf/main.cwl": {Data: []byte(`
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  echo:
+    run: ../tools/echo.cwl
+    in: []
+    out: []
+`)},
+		"tools/echo.cwl": {Data: []byte(`
+cwlVersion: v1.0
+class: CommandLineTool
+baseCommand: echo
+inputs:
+  $import: /tools/inputs.yaml
+outputs: []
+doc:
+  $include: doc.txt
+`)},
+		"tools/inputs.yaml": {Data: []byte(`
+msg: string
+`)},
+		"tools/doc.txt": {Data: []byte("Prints a message.")},
+	}
+
+	doc, err := LoadFS(fsys, "wf/main.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := doc.(*Workflow)
+	tool, ok := wf.Steps[0].Run.(*Tool)
+	if !ok {
+		t.Fatalf("expected the step to run a tool, got %#v", wf.Steps[0].Run)
+	}
+	if tool.SourceFile != "tools/echo.cwl" {
+		t.Errorf("unexpected source file: %q", tool.SourceFile)
+	}
+	if len(tool.Inputs) != 1 || tool.Inputs[0].ID != "msg" {
+		t.Errorf("unexpected imported inputs: %#v", tool.Inputs)
+	}
+	if tool.Doc != "Prints a message." {
+		t.Errorf("unexpected included doc: %q", tool.Doc)
+	}
+
+	_, err = LoadFS(fsys, "../wf/main.cwl")
+	if err == nil || !strings.Contains(err.Error(), "outside of the file system") {
+		t.Errorf("expected an error outside of the file system, got %v", err)
+	}
+}
+
+func TestLoadReader(t *testing.T) {
+	doc, err := LoadReader(strings.NewReader(cacheTestTool), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.(*Tool); !ok {
+		t.Errorf("expected a tool, got %T", doc)
+	}
+
+	// References are resolved by the given resolver.
+	fsys := fstest.MapFS{"tool.cwl": {Data: []byte(cacheTestTool)}}
+	wf := `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  a:
+    run: tool.cwl
+    in: []
+    out: []
+`
+	doc, err = LoadReader(strings.NewReader(wf), ".", FSResolver{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.(*Workflow).Steps[0].Run.(*Tool); !ok {
+		t.Errorf("expected the step to run a tool, got %#v", doc.(*Workflow).Steps[0].Run)
+	}
+}
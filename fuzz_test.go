@@ -0,0 +1,75 @@
+package cwl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exampleFiles returns the paths of the documents and input values
+// files in examples/, which seed the fuzz corpus.
+func exampleFiles(t testing.TB) []string {
+	var paths []string
+	err := filepath.Walk("examples", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".cwl", ".yaml", ".yml", ".json":
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func addExamples(f *testing.F) {
+	for _, path := range exampleFiles(f) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+}
+
+// TestLoadExamples loads every example, resolving references,
+// checking only that the loader doesn't panic.
+func TestLoadExamples(t *testing.T) {
+	for _, path := range exampleFiles(t) {
+		Load(path)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		LoadValuesBytes(b)
+	}
+}
+
+// FuzzLoad checks that loading a document never panics.
+// References are not resolved, so that fuzzed documents
+// can't read arbitrary files.
+func FuzzLoad(f *testing.F) {
+	addExamples(f)
+	dir := f.TempDir()
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		path := filepath.Join(dir, "doc.cwl")
+		if err := ioutil.WriteFile(path, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		LoadWithResolver(path, NoResolve())
+	})
+}
+
+// FuzzLoadValuesBytes checks that loading input values never panics.
+func FuzzLoadValuesBytes(f *testing.F) {
+	addExamples(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		LoadValuesBytes(b)
+	})
+}
@@ -0,0 +1,138 @@
+package cwl
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitResolver resolves documents from a local git repository at a
+// revision, without checking it out. Locations have the form
+// "repo.git@rev:path", such as "tools.git@v1.4:align/bwa.cwl", where
+// "rev" is a commit, tag or branch. The repository may be bare, or a
+// working copy, in which case "tools.git" may also refer to "tools".
+//
+// The revision is resolved to a commit once, so that relative references
+// inside the document resolve against the same commit.
+//
+// DefaultResolver also resolves these locations, using the "git" command.
+type GitResolver struct {
+	// Resolver resolves locations which aren't in a git repository.
+	// If nil, DefaultResolver is used.
+	Resolver Resolver
+}
+
+func (g GitResolver) Resolve(base, loc string) ([]byte, string, error) {
+	gl, ok := isGit(base, loc)
+	if !ok {
+		r := g.Resolver
+		if r == nil {
+			r = DefaultResolver{}
+		}
+		return r.Resolve(base, loc)
+	}
+	return gl.resolve()
+}
+
+// gitLocation is a file in a git repository, "repo.git@rev:path".
+type gitLocation struct {
+	repo, rev, path string
+}
+
+func (gl gitLocation) String() string {
+	return gl.repo + "@" + gl.rev + ":" + gl.path
+}
+
+// resolve reads the file, returning its bytes and the location of its
+// directory at the resolved commit, which is the base of its references.
+func (gl gitLocation) resolve() ([]byte, string, error) {
+	repo := gl.repo
+	if _, err := os.Stat(repo); err != nil {
+		if trimmed := strings.TrimSuffix(repo, ".git"); trimmed != repo {
+			if _, err := os.Stat(trimmed); err == nil {
+				repo = trimmed
+			}
+		}
+	}
+
+	// git would read a revision starting with "-" as an option.
+	if strings.HasPrefix(gl.rev, "-") {
+		return nil, "", errf("%s: invalid revision %q", gl, gl.rev)
+	}
+	out, err := git(repo, "rev-parse", "--verify", "--quiet", gl.rev+"^{commit}")
+	if err != nil {
+		return nil, "", errf("%s: unknown revision %q in %s", gl, gl.rev, gl.repo)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	b, err := git(repo, "cat-file", "blob", commit+":"+gl.path)
+	if err != nil {
+		return nil, "", errf("%s: %s", gl, err)
+	}
+	dir := gitLocation{repo: gl.repo, rev: commit, path: path.Dir(gl.path)}
+	return b, dir.String(), nil
+}
+
+// isGit returns the git location of "loc", which is either a git location,
+// or relative to a "base" in a git repository.
+func isGit(base, loc string) (gitLocation, bool) {
+	if gl, ok := parseGitLocation(loc); ok {
+		// A relative repository is relative to the referencing document.
+		if !filepath.IsAbs(gl.repo) && base != "" {
+			if _, ok := parseGitLocation(base); !ok {
+				gl.repo = filepath.Join(base, gl.repo)
+			}
+		}
+		return gl, true
+	}
+	gl, ok := parseGitLocation(base)
+	if !ok || filepath.IsAbs(loc) {
+		return gl, false
+	}
+	if _, ok := isHTTP("", loc); ok {
+		return gl, false
+	}
+	if strings.HasPrefix(loc, "/") {
+		gl.path = path.Clean(strings.TrimPrefix(loc, "/"))
+	} else {
+		gl.path = path.Join(gl.path, loc)
+	}
+	return gl, true
+}
+
+// parseGitLocation parses "repo.git@rev:path".
+func parseGitLocation(loc string) (gitLocation, bool) {
+	i := strings.Index(loc, ".git@")
+	if i == -1 {
+		return gitLocation{}, false
+	}
+	repo := loc[:i+len(".git")]
+	rest := loc[i+len(".git@"):]
+	j := strings.Index(rest, ":")
+	if j <= 0 {
+		return gitLocation{}, false
+	}
+	return gitLocation{
+		repo: repo,
+		rev:  rest[:j],
+		path: path.Clean(strings.TrimPrefix(rest[j+1:], "/")),
+	}, true
+}
+
+// git runs a git command in a repository, returning its output.
+func git(repo string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errf("%s", msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
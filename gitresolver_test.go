@@ -0,0 +1,111 @@
+package cwl
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitResolver(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "cwl-git-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	repo := filepath.Join(dir, "tools")
+
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		p := filepath.Join(repo, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os.MkdirAll(repo, 0755)
+	run("init", "-q")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "test")
+	write("wf/main.cwl", `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  a:
+    run: ../tools/a.cwl
+    in: []
+    out: []
+`)
+	write("tools/a.cwl", `
+cwlVersion: v1.0
+class: CommandLineTool
+baseCommand: v1
+inputs: []
+outputs: []
+`)
+	run("add", "-A")
+	run("commit", "-qm", "v1")
+	run("tag", "v1")
+
+	// Later changes aren't seen at the tag, even in the working copy.
+	write("tools/a.cwl", strings.Replace(cacheTestTool, "inputs", "baseCommand: v2\ninputs", 1))
+	run("commit", "-qam", "v2")
+
+	doc, err := Load(repo + ".git@v1:wf/main.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := doc.(*Workflow).Steps[0].Run.(*Tool)
+	if len(tool.BaseCommand) != 1 || tool.BaseCommand[0] != "v1" {
+		t.Errorf("expected the tool at v1, got %v", tool.BaseCommand)
+	}
+	if !strings.HasPrefix(tool.SourceFile, repo+".git@") || !strings.HasSuffix(tool.SourceFile, ":tools/a.cwl") {
+		t.Errorf("unexpected source file: %s", tool.SourceFile)
+	}
+
+	doc, err = LoadWithResolver(repo+".git@HEAD:tools/a.cwl", GitResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bc := doc.(*Tool).BaseCommand; len(bc) != 1 || bc[0] != "v2" {
+		t.Errorf("expected the tool at HEAD, got %v", bc)
+	}
+
+	_, err = Load(repo + ".git@v9:wf/main.cwl")
+	if err == nil || !strings.Contains(err.Error(), "unknown revision") {
+		t.Errorf("expected an unknown revision error, got %v", err)
+	}
+
+	_, err = Load(repo + ".git@--output=" + filepath.Join(dir, "x") + ":wf/main.cwl")
+	if err == nil || !strings.Contains(err.Error(), "invalid revision") {
+		t.Errorf("expected an invalid revision error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x")); err == nil {
+		t.Error("expected the revision not to be passed to git as an option")
+	}
+}
+
+func TestParseGitLocation(t *testing.T) {
+	gl, ok := parseGitLocation("lib/tools.git@v1.4:align/bwa.cwl")
+	if !ok || gl != (gitLocation{"lib/tools.git", "v1.4", "align/bwa.cwl"}) {
+		t.Errorf("unexpected location: %#v", gl)
+	}
+	for _, loc := range []string{"tools.cwl", "tools.git@v1.4", "https://example.com/tools.git"} {
+		if _, ok := parseGitLocation(loc); ok {
+			t.Errorf("%s isn't a git location", loc)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package cwl
+
+import (
+	"sort"
+	"strings"
+)
+
+// mainEntry is the entry point of a packed document, if none is selected.
+const mainEntry = "main"
+
+// Entry returns the document in the graph with the given ID,
+// such as "main" or "#main".
+func (g Graph) Entry(id string) (Document, bool) {
+	id = strings.TrimPrefix(id, "#")
+	for _, d := range g.Docs {
+		if strings.TrimPrefix(docID(d), "#") == id {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// IDs returns the IDs of the documents in the graph, sorted.
+func (g Graph) IDs() []string {
+	var ids []string
+	for _, d := range g.Docs {
+		if id := strings.TrimPrefix(docID(d), "#"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// splitEntry splits the entry point from a document location,
+// e.g. "packed.cwl#main" is split into "packed.cwl" and "main".
+func splitEntry(loc string) (string, string) {
+	if i := strings.LastIndex(loc, "#"); i != -1 {
+		return loc[:i], loc[i+1:]
+	}
+	return loc, ""
+}
+
+// graphEntries maps the IDs of the documents in a $graph to their nodes.
+// It returns nil if "root" isn't a packed document.
+func graphEntries(root node) map[string]node {
+	g, ok := findValue(root, "$graph")
+	if !ok {
+		return nil
+	}
+	entries := map[string]node{}
+	for _, c := range g.Children {
+		if id := findKey(c, "id"); id != "" {
+			entries[strings.TrimPrefix(id, "#")] = c
+		}
+	}
+	return entries
+}
+
+// loadEntry loads the document with the given ID from a packed document.
+func (l *loader) loadEntry(n node, id string) (Document, error) {
+	id = strings.TrimPrefix(id, "#")
+	entry, ok := l.graph[id]
+	if !ok {
+		var ids []string
+		for k := range l.graph {
+			ids = append(ids, k)
+		}
+		sort.Strings(ids)
+		return nil, l.errorAt(n, "no document with id %q in $graph, found: %s",
+			id, strings.Join(ids, ", "))
+	}
+
+	loc := l.file + "#" + id
+	if err := l.checkCycle(n, "run", loc); err != nil {
+		return nil, err
+	}
+	l.stack = append(l.stack, loc)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	var d Document
+	if err := l.load(entry, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
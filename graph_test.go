@@ -0,0 +1,106 @@
+package cwl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadPacked(t *testing.T) {
+	path := "examples/054-search/tool.cwl"
+
+	// "#main" is loaded by default, with in-graph references resolved.
+	d, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf, ok := d.(*Workflow)
+	if !ok || wf.ID != "main" {
+		t.Fatalf("expected the main workflow, got %#v", d)
+	}
+	index, ok := wf.Steps[0].Run.(*Tool)
+	if !ok || index.ID != "index" {
+		t.Errorf("expected #index to be resolved, got %#v", wf.Steps[0].Run)
+	}
+	if wf.SourceFile != path {
+		t.Errorf("unexpected source file: %s", wf.SourceFile)
+	}
+
+	// Entries can be selected by the location fragment, or by an option.
+	d, err = Load(path + "#search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool, ok := d.(*Tool); !ok || tool.ID != "search" {
+		t.Errorf("expected the search tool, got %#v", d)
+	}
+	d, _, err = LoadWithOptions(path, LoadOptions{Entry: "#index"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool, ok := d.(*Tool); !ok || tool.ID != "index" {
+		t.Errorf("expected the index tool, got %#v", d)
+	}
+
+	_, err = Load(path + "#missing")
+	if err == nil || !strings.Contains(err.Error(), `no document with id "missing" in $graph, found: index, main, search`) {
+		t.Errorf("expected missing entry error, got %v", err)
+	}
+
+	// NoResolve keeps the whole graph.
+	d, err = LoadWithResolver(path, NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ok := d.(Graph)
+	if !ok {
+		t.Fatalf("expected a graph, got %T", d)
+	}
+	if _, ok := g.Entry("#search"); !ok {
+		t.Errorf("expected a search entry in %v", g.IDs())
+	}
+}
+
+func TestLoadPackedReferences(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+$graph:
+  - id: main
+    class: Workflow
+    inputs: []
+    outputs: []
+    steps:
+      - id: loop
+        run: "#main"
+        in: []
+        out: []
+`
+	_, err := LoadDocumentBytes([]byte(doc), ".", DefaultResolver{})
+	if e, ok := err.(*LoadError); !ok || !strings.Contains(e.Error(), "run cycle") {
+		t.Errorf("expected run cycle error, got %v", err)
+	}
+
+	_, err = LoadDocumentBytes([]byte(`
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  - id: echo
+    run: "#echo"
+    in: []
+    out: []
+`), ".", DefaultResolver{})
+	if err == nil || !strings.Contains(err.Error(), "isn't packed") {
+		t.Errorf("expected error for an in-graph reference, got %v", err)
+	}
+}
+
+func TestGraphIDs(t *testing.T) {
+	g := Graph{Docs: []Document{&Tool{ID: "#b"}, &Tool{}, &Workflow{ID: "a"}}}
+	if ids := g.IDs(); strings.Join(ids, ",") != "a,b" {
+		t.Errorf("expected the entries with an id, got %v", ids)
+	}
+	if ids := (Graph{}).IDs(); len(ids) != 0 {
+		t.Errorf("expected no ids, got %v", ids)
+	}
+}
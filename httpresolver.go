@@ -0,0 +1,342 @@
+package cwl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultMaxSize is the size limit of a document fetched over HTTP,
+// if HTTPResolver.MaxSize isn't set.
+const DefaultMaxSize = 10 << 20
+
+// HTTPResolver resolves documents over HTTP(S), with the limits needed to
+// load untrusted URLs, such as in a service. Other locations, such as local
+// files, are resolved by Resolver, if the "file" scheme is allowed.
+//
+// The zero value fetches any HTTP(S) URL, with a 30 second timeout
+// and a DefaultMaxSize limit.
+type HTTPResolver struct {
+	// Resolver resolves locations which aren't HTTP URLs.
+	// If nil, DefaultResolver is used.
+	Resolver Resolver
+
+	// Timeout is the time limit of each request, including reading
+	// the document. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxSize is the size limit of a document, in bytes.
+	// Defaults to DefaultMaxSize. Negative means no limit.
+	MaxSize int64
+	// Retries is the number of times a request is retried after
+	// a network error, or a 429 or 5xx response.
+	Retries int
+	// RetryWait is the time to wait before the first retry, which
+	// doubles with each retry. Defaults to one second.
+	RetryWait time.Duration
+
+	// Schemes are the allowed schemes, such as "https". Local files have
+	// the "file" scheme. If empty, "http", "https" and "file" are allowed.
+	// Documents fetched over HTTP(S) can't refer to local files, whatever
+	// the schemes.
+	Schemes []string
+	// AllowHosts, if not empty, are the only hosts documents can be
+	// fetched from. "*.example.com" matches the subdomains of example.com.
+	AllowHosts []string
+	// DenyHosts are hosts documents can't be fetched from,
+	// matched like AllowHosts.
+	DenyHosts []string
+	// BlockPrivate blocks requests to private, loopback and link-local
+	// addresses, including hosts which resolve to them. The proxy set
+	// by HTTP_PROXY or HTTPS_PROXY isn't used, since it would connect
+	// to the host instead.
+	BlockPrivate bool
+
+	// Headers are added to the requests to a host, such as an
+	// "Authorization" header for a private registry. Keys are
+	// host names, or "host:port" to match a port.
+	Headers map[string]http.Header
+}
+
+func (h *HTTPResolver) Resolve(base, loc string) ([]byte, string, error) {
+	u, ok := isHTTP(base, loc)
+	if !ok {
+		if !h.allowScheme("file") {
+			return nil, "", errf("%s: local files aren't allowed", loc)
+		}
+		r := h.Resolver
+		if r == nil {
+			r = DefaultResolver{}
+		}
+		return r.Resolve(base, loc)
+	}
+
+	// A document fetched over HTTP may refer to "file:///etc/passwd".
+	if u.Scheme == "file" {
+		if b, err := url.Parse(base); err == nil && (b.Scheme == "http" || b.Scheme == "https") {
+			return nil, "", blockedError{fmt.Sprintf("%s: local files can't be referred to by %s", u, base)}
+		}
+		if !h.allowScheme("file") {
+			return nil, "", errf("%s: local files aren't allowed", u)
+		}
+		r := h.Resolver
+		if r == nil {
+			r = DefaultResolver{}
+		}
+		return r.Resolve("", u.Path)
+	}
+
+	_, body, err := h.get(u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, u.String(), nil
+}
+
+// revalidate implements revalidator, for CachingResolver.
+func (h *HTTPResolver) revalidate(u *url.URL, v validators) ([]byte, validators, bool, error) {
+	header := http.Header{}
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+	resp, body, err := h.get(u, header)
+	if err != nil {
+		return nil, v, false, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		if v == (validators{}) {
+			return nil, v, false, errf("failed to resolve HTTP URL %s: %s", u, resp.Status)
+		}
+		return nil, v, false, nil
+	}
+	next := validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	return body, next, true, nil
+}
+
+// get fetches a URL, with the given extra headers. It returns the
+// response, whose body has already been read, for a 2xx or 304 status.
+func (h *HTTPResolver) get(u *url.URL, header http.Header) (*http.Response, []byte, error) {
+	if err := h.checkURL(u); err != nil {
+		return nil, nil, err
+	}
+	client := h.client()
+
+	wait := h.RetryWait
+	if wait == 0 {
+		wait = time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := h.do(client, u, header)
+		retry := err != nil && !isBlocked(err)
+		if err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) {
+			err = errf("failed to resolve HTTP URL %s: %s", u, resp.Status)
+			retry = true
+		}
+		if err == nil {
+			return resp, body, nil
+		}
+		if !retry || attempt >= h.Retries {
+			return nil, nil, err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (h *HTTPResolver) do(client *http.Client, u *url.URL, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, nil, errf("failed to resolve HTTP URL %s: %s", u, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	h.addHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, errf("failed to resolve HTTP URL %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return resp, nil, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return resp, nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, nil, errf("failed to resolve HTTP URL %s: %s", u, resp.Status)
+	}
+
+	max := h.maxSize()
+	if max >= 0 && resp.ContentLength > max {
+		return nil, nil, errf("HTTP document %s is larger than the limit of %d bytes", u, max)
+	}
+	var r io.Reader = resp.Body
+	if max >= 0 {
+		r = io.LimitReader(resp.Body, max+1)
+	}
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, errf("failed to read HTTP response body for %s: %s", u, err)
+	}
+	if max >= 0 && int64(len(body)) > max {
+		return nil, nil, errf("HTTP document %s is larger than the limit of %d bytes", u, max)
+	}
+	return resp, body, nil
+}
+
+func (h *HTTPResolver) client() *http.Client {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	if h.BlockPrivate {
+		// Checked when connecting, so that a host can't
+		// resolve to a public address first, then a private one.
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+				return blockedError{fmt.Sprintf("address %s is private", host)}
+			}
+			return nil
+		}
+	}
+	proxy := http.ProxyFromEnvironment
+	if h.BlockPrivate {
+		// The dialer only sees the proxy's address, not the host's.
+		proxy = nil
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout: timeout,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errf("stopped after 10 redirects")
+			}
+			if err := h.checkURL(req.URL); err != nil {
+				return err
+			}
+			// Don't send one host's credentials to another.
+			for k := range h.hostHeaders(via[0].URL) {
+				req.Header.Del(k)
+			}
+			h.addHeaders(req)
+			return nil
+		},
+	}
+}
+
+// checkURL returns an error if the scheme or host of a URL isn't allowed.
+func (h *HTTPResolver) checkURL(u *url.URL) error {
+	if !h.allowScheme(u.Scheme) {
+		return blockedError{fmt.Sprintf("%s: scheme %q isn't allowed", u, u.Scheme)}
+	}
+	// "example.com." is the same host as "example.com".
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if len(h.AllowHosts) > 0 && !matchHost(h.AllowHosts, host) {
+		return blockedError{fmt.Sprintf("%s: host %q isn't allowed", u, host)}
+	}
+	if matchHost(h.DenyHosts, host) {
+		return blockedError{fmt.Sprintf("%s: host %q is denied", u, host)}
+	}
+	if h.BlockPrivate {
+		if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+			return blockedError{fmt.Sprintf("%s: address %s is private", u, host)}
+		}
+	}
+	return nil
+}
+
+func (h *HTTPResolver) allowScheme(scheme string) bool {
+	if len(h.Schemes) == 0 {
+		return scheme == "http" || scheme == "https" || scheme == "file"
+	}
+	for _, s := range h.Schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HTTPResolver) maxSize() int64 {
+	if h.MaxSize == 0 {
+		return DefaultMaxSize
+	}
+	return h.MaxSize
+}
+
+// hostHeaders returns the headers configured for the host of a URL.
+func (h *HTTPResolver) hostHeaders(u *url.URL) http.Header {
+	if hdr, ok := h.Headers[u.Host]; ok {
+		return hdr
+	}
+	return h.Headers[u.Hostname()]
+}
+
+func (h *HTTPResolver) addHeaders(req *http.Request) {
+	for k, v := range h.hostHeaders(req.URL) {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+}
+
+// matchHost returns true if the host matches one of the patterns,
+// such as "example.com" or "*.example.com". A trailing dot is ignored.
+func matchHost(patterns []string, host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	for _, p := range patterns {
+		p = strings.TrimSuffix(strings.ToLower(p), ".")
+		if p == host {
+			return true
+		}
+		if strings.HasPrefix(p, "*.") && strings.HasSuffix(host, p[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// blockedError is returned for URLs which aren't allowed. They aren't retried.
+type blockedError struct {
+	msg string
+}
+
+func (e blockedError) Error() string {
+	return e.msg
+}
+
+func isBlocked(err error) bool {
+	var b blockedError
+	return errors.As(err, &b)
+}
@@ -0,0 +1,162 @@
+package cwl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPResolver(t *testing.T) {
+	var failures int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool.cwl":
+			w.Write([]byte(cacheTestTool))
+		case "/big.cwl":
+			w.Write([]byte(strings.Repeat("#", 100)))
+		case "/flaky.cwl":
+			if failures < 2 {
+				failures++
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(cacheTestTool))
+		case "/private.cwl":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(cacheTestTool))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	h := &HTTPResolver{}
+	if _, err := LoadWithResolver(srv.URL+"/tool.cwl", h); err != nil {
+		t.Fatal(err)
+	}
+
+	h = &HTTPResolver{MaxSize: 50}
+	_, _, err := h.Resolve("", srv.URL+"/big.cwl")
+	if err == nil || !strings.Contains(err.Error(), "larger than the limit") {
+		t.Errorf("expected a size error, got %v", err)
+	}
+
+	h = &HTTPResolver{Retries: 1, RetryWait: time.Millisecond}
+	if _, _, err := h.Resolve("", srv.URL+"/flaky.cwl"); err == nil {
+		t.Error("expected an error after one retry")
+	}
+	h.Retries = 2
+	if _, _, err := h.Resolve("", srv.URL+"/flaky.cwl"); err != nil {
+		t.Errorf("expected success after retries, got %v", err)
+	}
+
+	h = &HTTPResolver{}
+	if _, _, err := h.Resolve("", srv.URL+"/private.cwl"); err == nil {
+		t.Error("expected an error without credentials")
+	}
+	h.Headers = map[string]http.Header{
+		u.Host: {"Authorization": {"Bearer secret"}},
+	}
+	if _, _, err := h.Resolve("", srv.URL+"/private.cwl"); err != nil {
+		t.Errorf("expected the host's header to be sent, got %v", err)
+	}
+
+	// The cache fetches documents with the resolver it wraps.
+	dir, err := ioutil.TempDir("", "cwl-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &CachingResolver{Resolver: h, Dir: dir}
+	if _, _, err := c.Resolve("", srv.URL+"/private.cwl"); err != nil {
+		t.Errorf("expected the cache to send the host's header, got %v", err)
+	}
+
+	blocked := []*HTTPResolver{
+		{AllowHosts: []string{"*.example.com"}},
+		{DenyHosts: []string{u.Hostname()}},
+		{BlockPrivate: true},
+		{Schemes: []string{"https"}},
+	}
+	for i, h := range blocked {
+		if _, _, err := h.Resolve("", srv.URL+"/tool.cwl"); err == nil || !isBlocked(err) {
+			t.Errorf("%d: expected the URL to be blocked, got %v", i, err)
+		}
+	}
+}
+
+func TestHTTPResolverProxy(t *testing.T) {
+	h := &HTTPResolver{}
+	if h.client().Transport.(*http.Transport).Proxy == nil {
+		t.Error("expected the proxy from the environment to be used")
+	}
+	h.BlockPrivate = true
+	if h.client().Transport.(*http.Transport).Proxy != nil {
+		t.Error("expected no proxy when private addresses are blocked")
+	}
+}
+
+func TestHTTPResolverFiles(t *testing.T) {
+	h := &HTTPResolver{Schemes: []string{"https"}}
+	_, _, err := h.Resolve("", "examples/023-count-lines1-wf/tool.cwl")
+	if err == nil || !strings.Contains(err.Error(), "local files aren't allowed") {
+		t.Errorf("expected a local file error, got %v", err)
+	}
+	_, _, err = h.Resolve("", "file:///etc/passwd")
+	if err == nil || !strings.Contains(err.Error(), "local files aren't allowed") {
+		t.Errorf("expected a file URL error, got %v", err)
+	}
+
+	h = &HTTPResolver{}
+	if _, _, err := h.Resolve("", "examples/023-count-lines1-wf/tool.cwl"); err != nil {
+		t.Errorf("expected local files by default, got %v", err)
+	}
+
+	// Documents fetched over HTTP can't refer to local files, even by default.
+	for _, base := range []string{"https://example.com/wf.cwl", "http://example.com/"} {
+		_, _, err = h.Resolve(base, "file:///etc/passwd")
+		if err == nil || !strings.Contains(err.Error(), "local files can't be referred to") {
+			t.Errorf("expected a file URL error for %s, got %v", base, err)
+		}
+	}
+	abs, _ := filepath.Abs("examples/023-count-lines1-wf/tool.cwl")
+	if _, _, err := h.Resolve("file://"+filepath.Dir(abs)+"/", "wc-tool.cwl"); err != nil {
+		t.Errorf("expected local files relative to a file URL, got %v", err)
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	patterns := []string{"example.com", "*.example.org"}
+	for host, want := range map[string]bool{
+		"example.com":      true,
+		"www.example.com":  false,
+		"www.example.org":  true,
+		"example.org":      false,
+		"badexample.org":   false,
+		"example.com.":     true,
+		"www.example.org.": true,
+	} {
+		if got := matchHost(patterns, host); got != want {
+			t.Errorf("matchHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	// A trailing dot doesn't get around DenyHosts.
+	h := &HTTPResolver{DenyHosts: []string{"internal.example.com"}}
+	for _, loc := range []string{"http://internal.example.com./wf.cwl", "http://INTERNAL.example.com.:80/wf.cwl"} {
+		u, _ := url.Parse(loc)
+		if err := h.checkURL(u); err == nil || !isBlocked(err) {
+			t.Errorf("expected %s to be denied, got %v", loc, err)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package cwl
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Index maps fully-qualified IDs, such as "file:///data/wf.cwl#step1/output",
+// to the inputs, outputs, steps and step ports of a document, and of the
+// documents it runs. IDs are stable across loads of the same files, so they
+// can be used for provenance and caching.
+//
+// Documents keep their short IDs, e.g. Step.ID is still "step1".
+// Use ID to find the fully-qualified ID of an object.
+type Index struct {
+	byID    map[string]interface{}
+	byObj   map[interface{}]string
+	sources map[string][]string
+	// files are the URIs of the files run by steps which were indexed.
+	files map[string]bool
+	// shared is non-zero while indexing a file which was already indexed,
+	// when several steps run the same file. Its objects share IDs.
+	shared int
+}
+
+// IndexIDs assigns a fully-qualified ID to every object in the document,
+// and resolves the references between them, such as step input sources.
+// "uri" is the location of the document, e.g. a file path or URL.
+// If empty, the document's SourceFile is used.
+//
+// Documents loaded through "run" are identified by their own file,
+// while embedded documents are scoped by the step which runs them.
+// Referenced documents (DocumentRef) aren't indexed.
+//
+// Unknown and duplicate IDs are returned as errors.
+func IndexIDs(doc Document, uri string) (*Index, error) {
+	if uri == "" {
+		uri = sourceFile(doc)
+	}
+	if uri == "" {
+		return nil, errf("the location of the document is unknown")
+	}
+	x := &Index{
+		byID:    map[string]interface{}{},
+		byObj:   map[interface{}]string{},
+		sources: map[string][]string{},
+		files:   map[string]bool{},
+	}
+	if err := x.doc(doc, fileURI(uri), ""); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Lookup returns the object with the given fully-qualified ID.
+// Objects are pointers into the document, such as a *Step, *StepInput,
+// *WorkflowInput or *CommandOutput, or a Document.
+func (x *Index) Lookup(id string) (interface{}, bool) {
+	obj, ok := x.byID[id]
+	return obj, ok
+}
+
+// ID returns the fully-qualified ID of an object, which must be
+// a pointer into the document, e.g. &wf.Steps[0].
+func (x *Index) ID(obj interface{}) (string, bool) {
+	id, ok := x.byObj[obj]
+	return id, ok
+}
+
+// IDs returns every fully-qualified ID in the index, sorted.
+func (x *Index) IDs() []string {
+	var ids []string
+	for id := range x.byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Sources returns the fully-qualified IDs of the sources of
+// a step input or workflow output, given its fully-qualified ID.
+func (x *Index) Sources(id string) []string {
+	return x.sources[id]
+}
+
+func (x *Index) add(id string, obj interface{}) error {
+	if x.shared > 0 {
+		if _, ok := x.byID[id]; !ok {
+			x.byID[id] = obj
+		}
+		x.byObj[obj] = id
+		return nil
+	}
+	if _, ok := x.byID[id]; ok {
+		return errf("duplicate id %q", id)
+	}
+	x.byID[id] = obj
+	x.byObj[obj] = id
+	return nil
+}
+
+// doc indexes a document. "base" is the URI of the file the document
+// came from, and "scope" is the fragment which the IDs of the document
+// are relative to, e.g. "main" for a $graph entry.
+func (x *Index) doc(doc Document, base, scope string) error {
+	switch z := doc.(type) {
+	case Graph:
+		for _, d := range z.Docs {
+			if docID(d) == "" {
+				return errf("%s: $graph document is missing an id", base)
+			}
+			if err := x.doc(d, base, scope); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case DocumentRef:
+		return nil
+	}
+
+	// A document's ID starts a new scope, e.g. "wf.cwl#main".
+	// An embedded document without an ID is identified by its scope,
+	// e.g. "wf.cwl#step1/run".
+	id := base
+	if d := docID(doc); d != "" {
+		id = qualify(base, scope, d)
+		scope = fragment(id)
+	} else if scope != "" {
+		id = base + "#" + scope
+	}
+	if err := x.add(id, doc); err != nil {
+		return err
+	}
+
+	switch z := doc.(type) {
+	case *Tool:
+		for i := range z.Inputs {
+			if err := x.add(qualify(base, scope, z.Inputs[i].ID), &z.Inputs[i]); err != nil {
+				return err
+			}
+		}
+		for i := range z.Outputs {
+			if err := x.add(qualify(base, scope, z.Outputs[i].ID), &z.Outputs[i]); err != nil {
+				return err
+			}
+		}
+
+	case *ExpressionTool:
+		for i := range z.Inputs {
+			if err := x.add(qualify(base, scope, z.Inputs[i].ID), &z.Inputs[i]); err != nil {
+				return err
+			}
+		}
+		for i := range z.Outputs {
+			if err := x.add(qualify(base, scope, z.Outputs[i].ID), &z.Outputs[i]); err != nil {
+				return err
+			}
+		}
+
+	case *Operation:
+		for i := range z.Inputs {
+			if err := x.add(qualify(base, scope, z.Inputs[i].ID), &z.Inputs[i]); err != nil {
+				return err
+			}
+		}
+		for i := range z.Outputs {
+			if err := x.add(qualify(base, scope, z.Outputs[i].ID), &z.Outputs[i]); err != nil {
+				return err
+			}
+		}
+
+	case *Workflow:
+		return x.workflow(z, base, scope)
+	}
+	return nil
+}
+
+func (x *Index) workflow(wf *Workflow, base, scope string) error {
+	for i := range wf.Inputs {
+		if err := x.add(qualify(base, scope, wf.Inputs[i].ID), &wf.Inputs[i]); err != nil {
+			return err
+		}
+	}
+
+	// Steps and ports are indexed before sources are resolved,
+	// since a source may come from any step.
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		id := qualify(base, scope, step.ID)
+		if err := x.add(id, step); err != nil {
+			return err
+		}
+		stepScope := fragment(id)
+		for j := range step.In {
+			if err := x.add(qualify(base, stepScope, step.In[j].ID), &step.In[j]); err != nil {
+				return err
+			}
+		}
+		for j := range step.Out {
+			if err := x.add(qualify(base, stepScope, step.Out[j].ID), &step.Out[j]); err != nil {
+				return err
+			}
+		}
+		if step.Run != nil {
+			if err := x.run(step.Run, base, stepScope); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range wf.Outputs {
+		out := &wf.Outputs[i]
+		id := qualify(base, scope, out.ID)
+		if err := x.add(id, out); err != nil {
+			return err
+		}
+		if err := x.resolveSources(id, out.OutputSource, base, scope); err != nil {
+			return errf("workflow output %q: %s", out.ID, err)
+		}
+	}
+	for _, step := range wf.Steps {
+		for j := range step.In {
+			in := &step.In[j]
+			id := x.byObj[in]
+			if err := x.resolveSources(id, in.Source, base, scope); err != nil {
+				return errf("step %q: input %q: %s", step.ID, in.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// run indexes the document run by a step. Documents loaded from a file
+// are identified by the file, while embedded documents are scoped
+// by the step, e.g. "wf.cwl#step1/run".
+func (x *Index) run(doc Document, base, stepScope string) error {
+	f := sourceFile(doc)
+	if f == "" {
+		return x.doc(doc, base, stepScope+"/run")
+	}
+
+	uri := fileURI(f)
+	if x.files[uri] {
+		x.shared++
+		defer func() { x.shared-- }()
+	}
+	x.files[uri] = true
+	return x.doc(doc, uri, "")
+}
+
+func (x *Index) resolveSources(id string, sources []string, base, scope string) error {
+	if x.shared > 0 {
+		return nil
+	}
+	for _, src := range sources {
+		ref, ok := x.resolve(src, base, scope)
+		if !ok {
+			return errf("unknown source %q", src)
+		}
+		x.sources[id] = append(x.sources[id], ref)
+	}
+	return nil
+}
+
+// resolve resolves a reference, such as "step1/output", "#step1/output"
+// or "#main/step1/output", against the workflow scope "scope".
+func (x *Index) resolve(ref, base, scope string) (string, bool) {
+	candidates := []string{qualify(base, scope, ref)}
+	// "#step1/output" is relative to the document, but it's
+	// common for packed workflows to leave out the workflow ID.
+	if strings.HasPrefix(ref, "#") && scope != "" {
+		candidates = append(candidates, qualify(base, scope, strings.TrimPrefix(ref, "#")))
+	}
+	for _, c := range candidates {
+		if _, ok := x.byID[c]; ok {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// qualify makes an ID fully-qualified. Absolute URIs are kept as they are,
+// IDs starting with "#" are relative to the document at "base", and other
+// IDs are relative to "scope".
+func qualify(base, scope, id string) string {
+	if u, err := url.Parse(id); err == nil && u.IsAbs() {
+		return id
+	}
+	if strings.HasPrefix(id, "#") {
+		return base + id
+	}
+	if scope != "" {
+		id = scope + "/" + id
+	}
+	return base + "#" + id
+}
+
+// fragment returns the part of an ID after "#".
+func fragment(id string) string {
+	if i := strings.Index(id, "#"); i != -1 {
+		return id[i+1:]
+	}
+	return ""
+}
+
+// fileURI converts a file path to a "file://" URI. URLs are kept as they are.
+func fileURI(loc string) string {
+	if u, err := url.Parse(loc); err == nil && u.IsAbs() && len(u.Scheme) > 1 {
+		return loc
+	}
+	if abs, err := filepath.Abs(loc); err == nil {
+		loc = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(loc)}
+	return u.String()
+}
+
+// sourceFile returns the location of the file a document was loaded from, if known.
+func sourceFile(doc Document) string {
+	switch z := doc.(type) {
+	case *Tool:
+		return z.SourceFile
+	case *Workflow:
+		return z.SourceFile
+	case *ExpressionTool:
+		return z.SourceFile
+	}
+	return ""
+}
+
+func setSourceFile(doc Document, loc string) {
+	switch z := doc.(type) {
+	case *Tool:
+		z.SourceFile = loc
+	case *Workflow:
+		z.SourceFile = loc
+	case *ExpressionTool:
+		z.SourceFile = loc
+	}
+}
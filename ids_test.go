@@ -0,0 +1,114 @@
+package cwl
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexIDs(t *testing.T) {
+	path := "examples/023-count-lines1-wf/tool.cwl"
+	d, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := d.(*Workflow)
+
+	idx, err := IndexIDs(wf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, _ := filepath.Abs(path)
+	dir := filepath.Dir(abs)
+	uri := "file://" + abs
+
+	id, ok := idx.ID(&wf.Steps[0].Out[0])
+	if !ok || id != uri+"#step1/output" {
+		t.Errorf("unexpected step output ID: %s", id)
+	}
+	if obj, ok := idx.Lookup(uri + "#step2"); !ok || obj != &wf.Steps[1] {
+		t.Errorf("unexpected lookup result: %#v", obj)
+	}
+
+	// Documents loaded through "run" keep their origin.
+	wc := wf.Steps[0].Run.(*Tool)
+	id, _ = idx.ID(&wc.Inputs[0])
+	if id != "file://"+dir+"/wc-tool.cwl#file1" {
+		t.Errorf("unexpected tool input ID: %s", id)
+	}
+
+	sources := idx.Sources(uri + "#step2/file1")
+	if !reflect.DeepEqual(sources, []string{uri + "#step1/output"}) {
+		t.Errorf("unexpected step input sources: %v", sources)
+	}
+	sources = idx.Sources(uri + "#count_output")
+	if !reflect.DeepEqual(sources, []string{uri + "#step2/output"}) {
+		t.Errorf("unexpected workflow output sources: %v", sources)
+	}
+}
+
+func TestIndexIDsGraph(t *testing.T) {
+	d, err := LoadWithResolver("examples/054-search/tool.cwl", NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := IndexIDs(d, "http://example.com/search.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := "http://example.com/search.cwl#"
+	for _, id := range []string{"index", "index/file", "main", "main/infile", "main/search/result"} {
+		if _, ok := idx.Lookup(base + id); !ok {
+			t.Errorf("missing ID %s", base+id)
+		}
+	}
+	sources := idx.Sources(base + "main/search/file")
+	if !reflect.DeepEqual(sources, []string{base + "main/index/result"}) {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+
+	_, err = IndexIDs(&Workflow{
+		Outputs: []WorkflowOutput{{ID: "out", OutputSource: []string{"nope/out"}}},
+	}, "wf.cwl")
+	if err == nil {
+		t.Error("expected unknown source error")
+	}
+}
+
+// TestIndexIDsExamples checks that every example workflow is indexed
+// without duplicate or unknown IDs.
+func TestIndexIDsExamples(t *testing.T) {
+	for _, path := range exampleFiles(t) {
+		d, err := Load(path)
+		if err != nil {
+			// Not a document, such as an input values file.
+			continue
+		}
+		switch d.(type) {
+		case *Workflow, Graph:
+		default:
+			continue
+		}
+		if _, err := IndexIDs(d, path); err != nil {
+			t.Errorf("%s: %s", path, err)
+		}
+	}
+
+	// An embedded document without an ID is identified by its step.
+	d, err := Load("examples/024-count-lines2-wf/tool.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := d.(*Workflow)
+	idx, err := IndexIDs(wf, "http://example.com/wf.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := idx.ID(wf.Steps[1].Run); id != "http://example.com/wf.cwl#step2/run" {
+		t.Errorf("unexpected embedded document ID: %s", id)
+	}
+	if _, ok := idx.Lookup("http://example.com/wf.cwl#step2/run/parseInt_file1"); !ok {
+		t.Errorf("missing embedded document input in %v", idx.IDs())
+	}
+}
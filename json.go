@@ -2,8 +2,25 @@ package cwl
 
 import (
 	"encoding/json"
+	"github.com/go-yaml/yaml"
+	"strconv"
 )
 
+// MarshalYAML marshals a document, or any value, to YAML. The document
+// types describe their CWL fields, such as "class", with MarshalJSON,
+// so the YAML is converted from JSON, keeping the order of the fields.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var y yaml.MapSlice
+	if err := yaml.Unmarshal(b, &y); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(y)
+}
+
 // A bunch of tedious wrappers for fields like "class" and "type"
 // so that they marhshal to JSON/YAML correctly.
 
@@ -52,105 +69,305 @@ func (i OutputRecord) MarshalJSON() ([]byte, error) {
 		Wrap
 	}{"record", Wrap(i)})
 }
+
+func (i InputEnum) MarshalJSON() ([]byte, error) {
+	type Wrap InputEnum
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Wrap
+	}{"enum", Wrap(i)})
+}
+
+func (i OutputEnum) MarshalJSON() ([]byte, error) {
+	type Wrap OutputEnum
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Wrap
+	}{"enum", Wrap(i)})
+}
+
+// A schema def is written as its type, with a name.
+func (x SchemaDef) MarshalJSON() ([]byte, error) {
+	switch z := x.Type.(type) {
+	case InputRecord:
+		z.Name = x.Name
+		return json.Marshal(z)
+	case InputEnum:
+		z.Name = x.Name
+		return json.Marshal(z)
+	case InputArray:
+		z.Name = x.Name
+		return json.Marshal(z)
+	}
+	return nil, errf("unknown schema type for %s: %T", x.Name, x.Type)
+}
+
+// Secondary files without "required" are written as
+// a pattern string, as in v1.0.
+func (x SecondaryFile) MarshalJSON() ([]byte, error) {
+	if x.Required == "" {
+		return json.Marshal(x.Pattern)
+	}
+	return json.Marshal(struct {
+		Pattern  Expression  `json:"pattern"`
+		Required interface{} `json:"required"`
+	}{x.Pattern, literal(x.Required)})
+}
+
+// literal returns the value of an expression field which holds
+// a literal bool or int, such as "position: 1", so that it's
+// written as a bool or int rather than a string.
+func literal(x Expression) interface{} {
+	s := string(x)
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	return s
+}
+
+// Unset OptOut fields are left out, rather than written as true,
+// so that they stay unset when loaded again.
+func (x CommandLineBinding) MarshalJSON() ([]byte, error) {
+	type Wrap CommandLineBinding
+	var pos interface{}
+	if x.Position != "" {
+		pos = literal(x.Position)
+	}
+	return json.Marshal(struct {
+		Wrap
+		Position   interface{} `json:"position,omitempty"`
+		Separate   *bool       `json:"separate,omitempty"`
+		ShellQuote *bool       `json:"shellQuote,omitempty"`
+	}{Wrap(x), pos, x.Separate.ptr(), x.ShellQuote.ptr()})
+}
 func (x Workflow) MarshalJSON() ([]byte, error) {
 	type Wrap Workflow
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"Workflow", Wrap(x)})
+		Hints        requirementList `json:"hints,omitempty"`
+		Requirements requirementList `json:"requirements,omitempty"`
+	}{"Workflow", Wrap(x), x.Hints, x.Requirements}, x.Extensions)
 }
 
 func (x Tool) MarshalJSON() ([]byte, error) {
 	type Wrap Tool
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+		Hints        requirementList `json:"hints,omitempty"`
+		Requirements requirementList `json:"requirements,omitempty"`
+	}{"CommandLineTool", Wrap(x), x.Hints, x.Requirements}, x.Extensions)
+}
+
+func (x ExpressionTool) MarshalJSON() ([]byte, error) {
+	type Wrap ExpressionTool
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+		Hints        requirementList `json:"hints,omitempty"`
+		Requirements requirementList `json:"requirements,omitempty"`
+	}{"ExpressionTool", Wrap(x), x.Hints, x.Requirements}, x.Extensions)
+}
+
+func (x Operation) MarshalJSON() ([]byte, error) {
+	type Wrap Operation
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+		Hints        requirementList `json:"hints,omitempty"`
+		Requirements requirementList `json:"requirements,omitempty"`
+	}{"Operation", Wrap(x), x.Hints, x.Requirements}, x.Extensions)
+}
+
+func (x Script) MarshalJSON() ([]byte, error) {
+	type Wrap Script
 	return json.Marshal(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"CommandLineTool", Wrap(x)})
+		Hints        requirementList `json:"hints,omitempty"`
+		Requirements requirementList `json:"requirements,omitempty"`
+	}{"ScriptTool", Wrap(x), x.Hints, x.Requirements})
+}
+
+func (x Graph) MarshalJSON() ([]byte, error) {
+	type Wrap Graph
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+func (x Step) MarshalJSON() ([]byte, error) {
+	type Wrap Step
+	return marshalExtensions(struct {
+		Wrap
+		Hints        requirementList `json:"hints,omitempty"`
+		Requirements requirementList `json:"requirements,omitempty"`
+	}{Wrap(x), x.Hints, x.Requirements}, x.Extensions)
+}
+
+func (x CommandInput) MarshalJSON() ([]byte, error) {
+	type Wrap CommandInput
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+func (x CommandOutput) MarshalJSON() ([]byte, error) {
+	type Wrap CommandOutput
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+func (x WorkflowInput) MarshalJSON() ([]byte, error) {
+	type Wrap WorkflowInput
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+func (x WorkflowOutput) MarshalJSON() ([]byte, error) {
+	type Wrap WorkflowOutput
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+func (x StepInput) MarshalJSON() ([]byte, error) {
+	type Wrap StepInput
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+func (x StepOutput) MarshalJSON() ([]byte, error) {
+	type Wrap StepOutput
+	return marshalExtensions(Wrap(x), x.Extensions)
+}
+
+// The fields of an unknown requirement are kept in its Extensions.
+func (x UnknownRequirement) MarshalJSON() ([]byte, error) {
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+	}{x.Name}, x.Extensions)
 }
 
 func (x DockerRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap DockerRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"DockerRequirement", Wrap(x)})
+	}{"DockerRequirement", Wrap(x)}, x.Extensions)
 }
 func (x ResourceRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap ResourceRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"ResourceRequirement", Wrap(x)})
+	}{"ResourceRequirement", Wrap(x)}, x.Extensions)
 }
 func (x EnvVarRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap EnvVarRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"EnvVarRequirement", Wrap(x)})
+	}{"EnvVarRequirement", Wrap(x)}, x.Extensions)
 }
 func (x SchemaDefRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap SchemaDefRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"SchemaDefRequirement", Wrap(x)})
+	}{"SchemaDefRequirement", Wrap(x)}, x.Extensions)
 }
 func (x ShellCommandRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap ShellCommandRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"ShellCommandRequirement", Wrap(x)})
+	}{"ShellCommandRequirement", Wrap(x)}, x.Extensions)
 }
 func (x InlineJavascriptRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap InlineJavascriptRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"InlineJavascriptRequirement", Wrap(x)})
+	}{"InlineJavascriptRequirement", Wrap(x)}, x.Extensions)
 }
 func (x SoftwareRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap SoftwareRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"SoftwareRequirement", Wrap(x)})
+	}{"SoftwareRequirement", Wrap(x)}, x.Extensions)
 }
 func (x InitialWorkDirRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap InitialWorkDirRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"InitialWorkDirRequirement", Wrap(x)})
+	}{"InitialWorkDirRequirement", Wrap(x)}, x.Extensions)
 }
 func (x SubworkflowFeatureRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap SubworkflowFeatureRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"SubworkflowFeatureRequirement", Wrap(x)})
+	}{"SubworkflowFeatureRequirement", Wrap(x)}, x.Extensions)
 }
 func (x ScatterFeatureRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap ScatterFeatureRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"ScatterFeatureRequirement", Wrap(x)})
+	}{"ScatterFeatureRequirement", Wrap(x)}, x.Extensions)
 }
 func (x MultipleInputFeatureRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap MultipleInputFeatureRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"MultipleInputFeatureRequirement", Wrap(x)})
+	}{"MultipleInputFeatureRequirement", Wrap(x)}, x.Extensions)
 }
 func (x StepInputExpressionRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap StepInputExpressionRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"StepInputExpressionRequirement", Wrap(x)}, x.Extensions)
+}
+func (x ToolTimeLimit) MarshalJSON() ([]byte, error) {
+	type Wrap ToolTimeLimit
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+		Timelimit interface{} `json:"timelimit"`
+	}{"ToolTimeLimit", Wrap(x), literal(x.Timelimit)}, x.Extensions)
+}
+func (x LoadListingRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap LoadListingRequirement
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"StepInputExpressionRequirement", Wrap(x)})
+	}{"LoadListingRequirement", Wrap(x)}, x.Extensions)
+}
+func (x NetworkAccess) MarshalJSON() ([]byte, error) {
+	type Wrap NetworkAccess
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+		NetworkAccess interface{} `json:"networkAccess"`
+	}{"NetworkAccess", Wrap(x), literal(x.NetworkAccess)}, x.Extensions)
+}
+func (x WorkReuse) MarshalJSON() ([]byte, error) {
+	type Wrap WorkReuse
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+		EnableReuse interface{} `json:"enableReuse"`
+	}{"WorkReuse", Wrap(x), literal(x.EnableReuse)}, x.Extensions)
+}
+func (x InplaceUpdateRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap InplaceUpdateRequirement
+	return marshalExtensions(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"InplaceUpdateRequirement", Wrap(x)}, x.Extensions)
 }
 /*
 func (x PreCMDRequirement) MarshalJSON() ([]byte, error) {
@@ -171,8 +388,8 @@ func (x PostCMDRequirement) MarshalJSON() ([]byte, error) {
 */
 func (x LRMRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap LRMRequirement
-	return json.Marshal(struct {
+	return marshalExtensions(struct {
 		Class string `json:"class"`
 		Wrap
-	}{"LRMRequirement", Wrap(x)})
+	}{"LRMRequirement", Wrap(x)}, x.Extensions)
 }
\ No newline at end of file
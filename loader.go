@@ -18,14 +18,53 @@ import (
 type loader struct {
 	base     string
 	resolver Resolver
+
+	// file is the location of the document being loaded, if known.
+	file string
+	// root is the root node of the document, used to find the
+	// position of nodes in errors. See LoadError.
+	root node
+	// origins maps the root nodes of imported subtrees
+	// to the file they were imported from.
+	origins map[*yamlast.Node]origin
+	// pending holds the root nodes of documents being imported,
+	// which are not part of the tree yet.
+	pending []node
+	// positions holds the position of every node of "indexed",
+	// the root once preprocessed. See indexPositions.
+	positions map[*yamlast.Node]position
+	indexed   node
+	// strict fails loading on unknown fields, instead of warning.
+	strict bool
+	// warnings collects warnings, if not nil.
+	warnings *[]*LoadError
+	// namespaces maps the prefixes declared in "$namespaces" to URIs.
+	namespaces map[string]string
+	// version is the "cwlVersion" of the document. Fields and classes
+	// added in later versions are reported like unknown fields.
+	version string
+	// graph maps the IDs of the documents of a packed ($graph)
+	// document to their nodes, so that "run" can refer to them.
+	graph map[string]node
+	// stack holds the locations of the documents being loaded,
+	// outermost first, in order to detect import and run cycles.
+	stack []string
 }
 
 // load is given a YAML node and a destination type,
 // e.g. yamlast.Mapping -> cwl.WorkflowInput.
 //
-// load() panics if `t` is not a pointer.
-// load() panics if given an unknown YAML node type (such as Alias)
+// Errors are returned as a *LoadError, positioned at the
+// deepest node which failed to load.
 func (l *loader) load(n node, t interface{}) error {
+	err := l.loadNode(n, t)
+	if err != nil {
+		return l.wrapErr(n, err)
+	}
+	return nil
+}
+
+func (l *loader) loadNode(n node, t interface{}) error {
 
 	// only pointers can be set to new values by the loader.
 	if reflect.TypeOf(t).Kind() != reflect.Ptr {
@@ -51,8 +90,11 @@ func (l *loader) load(n node, t interface{}) error {
 		nodeKind = "Seq"
 	case yamlast.ScalarNode:
 		nodeKind = "Scalar"
+	case yamlast.AliasNode:
+		// Aliases are expanded when parsing, see parseYAML().
+		return fmt.Errorf("unresolved YAML alias")
 	default:
-		panic("unknown node kind")
+		return fmt.Errorf("unexpected YAML node kind")
 	}
 
 	// describes the type conversion being requested,
@@ -129,21 +171,21 @@ func (l *loader) load(n node, t interface{}) error {
 	}
 
 	// No handler found.
-	return fmt.Errorf("unhandled type at line %d, col %d. looking for %s", n.Line+1, n.Column+1, handlerName)
+	return fmt.Errorf("unhandled type, looking for %s", handlerName)
 }
 
 // loadMappingToStruct essentially unmarshals a YAML mapping
 // into a Go struct.
 //
-// "n" must be a mapping node.
+// "n" should be a mapping node, otherwise an error is returned.
 // "t" must be a pointer to a struct.
 func (l *loader) loadMappingToStruct(n node, t interface{}) error {
 
 	if n.Kind != yamlast.MappingNode {
-		panic("expected mapping node")
+		return fmt.Errorf("expected a mapping")
 	}
 	if len(n.Children)%2 != 0 {
-		panic("expected even number of children in mapping")
+		return fmt.Errorf("expected even number of children in mapping")
 	}
 
 	typ := reflect.TypeOf(t).Elem()
@@ -158,7 +200,7 @@ func (l *loader) loadMappingToStruct(n node, t interface{}) error {
 		name := strings.ToLower(k.Value)
 
 		if _, ok := already[name]; ok {
-			return fmt.Errorf("duplicate field found while loading mapping")
+			return l.errorAt(k, "duplicate field %q found while loading mapping", k.Value)
 		}
 		already[name] = true
 
@@ -172,7 +214,9 @@ func (l *loader) loadMappingToStruct(n node, t interface{}) error {
 			n := f.Name
 			if alt, ok := f.Tag.Lookup("json"); ok {
 				sp := strings.Split(alt, ",")
-				n = sp[0]
+				if sp[0] != "" {
+					n = sp[0]
+				}
 			}
 
 			if strings.ToLower(n) == name {
@@ -183,9 +227,24 @@ func (l *loader) loadMappingToStruct(n node, t interface{}) error {
 		}
 
 		if !found {
+			if err := l.unknownField(k, typ); err != nil {
+				return err
+			}
+			if err := l.keepExtension(k, v, val); err != nil {
+				return err
+			}
 			continue
 		}
 
+		// Fields added in later versions of CWL have a "cwl" tag,
+		// e.g. `cwl:"v1.2"`.
+		if since := field.Tag.Get("cwl"); since != "" {
+			what := fmt.Sprintf("field %q", k.Value)
+			if err := l.checkVersion(k, since, what); err != nil {
+				return err
+			}
+		}
+
 		fv := val.FieldByIndex(field.Index)
 
 		if !fv.CanSet() {
@@ -0,0 +1,117 @@
+package cwl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+// Lockfile records the sha256 digest of every document resolved while
+// loading a document, so that later loads can verify that none of them
+// changed, e.g. a "run" document fetched over HTTP.
+//
+// Documents are keyed by location: a URL, a git location, or an absolute
+// file path, so that a lockfile verifies from any working directory.
+// The paths of an FSResolver are kept relative to the root of its file system.
+type Lockfile struct {
+	// Documents maps the location of each document to its digest,
+	// such as "sha256:9f86d0...".
+	Documents map[string]string `json:"documents"`
+
+	mtx sync.Mutex
+}
+
+// LoadLockfile reads a lockfile written by Lockfile.WriteFile.
+func LoadLockfile(path string) (*Lockfile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lf := &Lockfile{}
+	if err := json.Unmarshal(b, lf); err != nil {
+		return nil, errf("failed to parse lockfile %s: %s", path, err)
+	}
+	return lf, nil
+}
+
+// WriteFile writes the lockfile as JSON.
+func (lf *Lockfile) WriteFile(path string) error {
+	lf.mtx.Lock()
+	b, err := json.MarshalIndent(lf, "", "  ")
+	lf.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// Record returns a resolver which resolves documents with "r",
+// recording their digests in the lockfile.
+func (lf *Lockfile) Record(r Resolver) Resolver {
+	return &lockResolver{resolver: r, lock: lf}
+}
+
+// Verify returns a resolver which resolves documents with "r", failing
+// if a document's digest doesn't match the lockfile, or if the document
+// isn't in the lockfile.
+func (lf *Lockfile) Verify(r Resolver) Resolver {
+	return &lockResolver{resolver: r, lock: lf, verify: true}
+}
+
+type lockResolver struct {
+	resolver Resolver
+	lock     *Lockfile
+	verify   bool
+}
+
+func (l *lockResolver) Resolve(base, loc string) ([]byte, string, error) {
+	b, newBase, err := l.resolver.Resolve(base, loc)
+	if err != nil {
+		return nil, "", err
+	}
+	key := l.key(base, loc)
+	sum := sha256.Sum256(b)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	lf := l.lock
+	lf.mtx.Lock()
+	defer lf.mtx.Unlock()
+
+	if !l.verify {
+		if lf.Documents == nil {
+			lf.Documents = map[string]string{}
+		}
+		lf.Documents[key] = digest
+		return b, newBase, nil
+	}
+
+	locked, ok := lf.Documents[key]
+	if !ok {
+		return nil, "", errf("%s isn't in the lockfile", key)
+	}
+	if locked != digest {
+		return nil, "", errf("%s has changed: its digest is %s, but the lockfile has %s", key, digest, locked)
+	}
+	return b, newBase, nil
+}
+
+// key returns the lockfile key of a document.
+func (l *lockResolver) key(base, loc string) string {
+	key, _ := splitEntry(docLocation(base, loc))
+	if _, ok := l.resolver.(FSResolver); ok {
+		return key
+	}
+	if _, ok := isHTTP(base, loc); ok {
+		return key
+	}
+	if _, ok := isGit(base, loc); ok {
+		return key
+	}
+	if abs, err := filepath.Abs(key); err == nil {
+		return abs
+	}
+	return key
+}
@@ -0,0 +1,114 @@
+package cwl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLockfile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"wf.cwl": {Data: []byte(`
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  a:
+    run: tools/a.cwl
+    in: []
+    out: []
+`)},
+		"tools/a.cwl": {Data: []byte(cacheTestTool)},
+	}
+	r := FSResolver{FS: fsys}
+
+	lf := &Lockfile{}
+	if _, err := LoadWithResolver("wf.cwl", lf.Record(r)); err != nil {
+		t.Fatal(err)
+	}
+	if len(lf.Documents) != 2 || !strings.HasPrefix(lf.Documents["tools/a.cwl"], "sha256:") {
+		t.Fatalf("unexpected lockfile: %v", lf.Documents)
+	}
+
+	dir, err := ioutil.TempDir("", "cwl-lock-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "cwl.lock")
+	if err := lf.WriteFile(p); err != nil {
+		t.Fatal(err)
+	}
+	lf, err = LoadLockfile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LoadOptions{Resolver: r, Lock: lf}
+	if _, _, err := LoadWithOptions("wf.cwl", opts); err != nil {
+		t.Errorf("expected the unchanged documents to verify, got %v", err)
+	}
+
+	fsys["tools/a.cwl"] = &fstest.MapFile{Data: []byte(cacheTestTool + "doc: changed\n")}
+	_, _, err = LoadWithOptions("wf.cwl", opts)
+	if err == nil || !strings.Contains(err.Error(), "tools/a.cwl has changed") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+
+	delete(lf.Documents, "tools/a.cwl")
+	_, _, err = LoadWithOptions("wf.cwl", opts)
+	if err == nil || !strings.Contains(err.Error(), "isn't in the lockfile") {
+		t.Errorf("expected a missing document error, got %v", err)
+	}
+}
+
+func TestLockfileWorkingDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"wf.cwl": `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  a:
+    run: tools/a.cwl
+    in: []
+    out: []
+`,
+		"tools/a.cwl": cacheTestTool,
+	})
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// Local files are keyed by absolute path, so the lockfile
+	// verifies from another working directory.
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	lf := &Lockfile{}
+	if _, err := LoadWithResolver("wf.cwl", lf.Record(DefaultResolver{})); err != nil {
+		t.Fatal(err)
+	}
+	for key := range lf.Documents {
+		if !filepath.IsAbs(key) {
+			t.Errorf("expected an absolute path, got %s", key)
+		}
+	}
+
+	if err := os.Chdir(filepath.Dir(dir)); err != nil {
+		t.Fatal(err)
+	}
+	opts := LoadOptions{Resolver: DefaultResolver{}, Lock: lf}
+	if _, _, err := LoadWithOptions(filepath.Join(filepath.Base(dir), "wf.cwl"), opts); err != nil {
+		t.Errorf("expected the documents to verify from another directory, got %v", err)
+	}
+}
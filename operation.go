@@ -0,0 +1,18 @@
+package cwl
+
+// Operation is an abstract process, which describes the inputs and outputs
+// of a step without saying how it runs. Added in v1.2.
+type Operation struct {
+	CWLVersion string `json:"cwlVersion,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Label      string `json:"label,omitempty"`
+	Doc        string `json:"doc,omitempty"`
+
+	Hints        []Requirement `json:"hints,omitempty"`
+	Requirements []Requirement `json:"requirements,omitempty"`
+
+	Inputs  []WorkflowInput  `json:"inputs,omitempty"`
+	Outputs []WorkflowOutput `json:"outputs,omitempty"`
+
+	Extensions Extensions `json:"-"`
+}
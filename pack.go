@@ -0,0 +1,438 @@
+package cwl
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// Pack combines a document and the documents it runs into a single packed
+// ($graph) document. The document must be loaded with a Resolver, so that
+// "run", "$import" and "$include" references are already resolved.
+//
+// The document becomes "#main", and each file run by a step becomes
+// an entry named after the file, such as "#wc-tool", with "run" rewritten
+// to refer to it. Documents embedded in a step stay where they are.
+// Type references to SchemaDefRequirement types in other files, such as
+// "types.yml#Sample", are rewritten to "#Sample".
+//
+// The documents are copied, so "doc" isn't changed.
+func Pack(doc Document) (Graph, error) {
+	if g, ok := doc.(Graph); ok {
+		return g, nil
+	}
+	p := packer{
+		ids:    map[string]bool{mainEntry: true},
+		byFile: map[string]string{},
+	}
+	main, err := p.doc(doc, "")
+	if err != nil {
+		return Graph{}, err
+	}
+	setDocID(main, mainEntry)
+
+	p.graph.CWLVersion = p.version
+	p.graph.Docs = append([]Document{main}, p.graph.Docs...)
+	return p.graph, nil
+}
+
+type packer struct {
+	graph Graph
+	// version is the cwlVersion of the documents, which must all be the same.
+	version string
+	// ids are the IDs of the graph entries.
+	ids map[string]bool
+	// byFile maps the files run by steps to their graph entry IDs.
+	byFile map[string]string
+}
+
+// doc copies a document, rewriting it for the graph.
+// "file" is the location of the document, used in errors.
+func (p *packer) doc(doc Document, file string) (Document, error) {
+	if f := sourceFile(doc); f != "" {
+		file = f
+	}
+
+	switch z := doc.(type) {
+	case *Tool:
+		c := *z
+		c.SourceFile = ""
+		if err := p.common(&c.CWLVersion, &c.Extensions, file); err != nil {
+			return nil, err
+		}
+		c.Inputs = append([]CommandInput(nil), z.Inputs...)
+		for i := range c.Inputs {
+			c.Inputs[i].Type = packInputTypes(c.Inputs[i].Type)
+		}
+		c.Outputs = append([]CommandOutput(nil), z.Outputs...)
+		for i := range c.Outputs {
+			c.Outputs[i].Type = packOutputTypes(c.Outputs[i].Type)
+		}
+		return &c, nil
+
+	case *ExpressionTool:
+		c := *z
+		c.SourceFile = ""
+		if err := p.common(&c.CWLVersion, &c.Extensions, file); err != nil {
+			return nil, err
+		}
+		c.Inputs = append([]CommandInput(nil), z.Inputs...)
+		for i := range c.Inputs {
+			c.Inputs[i].Type = packInputTypes(c.Inputs[i].Type)
+		}
+		c.Outputs = append([]CommandOutput(nil), z.Outputs...)
+		for i := range c.Outputs {
+			c.Outputs[i].Type = packOutputTypes(c.Outputs[i].Type)
+		}
+		return &c, nil
+
+	case *Operation:
+		c := *z
+		if err := p.common(&c.CWLVersion, &c.Extensions, file); err != nil {
+			return nil, err
+		}
+		return &c, nil
+
+	case *Script:
+		c := *z
+		if err := p.common(&c.CWLVersion, nil, file); err != nil {
+			return nil, err
+		}
+		return &c, nil
+
+	case *Workflow:
+		c := *z
+		c.SourceFile = ""
+		if err := p.common(&c.CWLVersion, &c.Extensions, file); err != nil {
+			return nil, err
+		}
+		c.Inputs = append([]WorkflowInput(nil), z.Inputs...)
+		for i := range c.Inputs {
+			c.Inputs[i].Type = packInputTypes(c.Inputs[i].Type)
+		}
+		c.Outputs = append([]WorkflowOutput(nil), z.Outputs...)
+		for i := range c.Outputs {
+			c.Outputs[i].Type = packOutputTypes(c.Outputs[i].Type)
+		}
+
+		c.Steps = append([]Step(nil), z.Steps...)
+		for i := range c.Steps {
+			step := &c.Steps[i]
+			run, err := p.run(step.Run, file)
+			if err != nil {
+				return nil, errf("%s: step %q: %s", file, step.ID, err)
+			}
+			step.Run = run
+		}
+		return &c, nil
+
+	case DocumentRef:
+		return nil, errf("%s: %s isn't resolved, load the document with a Resolver", file, z.Location)
+	}
+	return nil, errf("%s: can't pack a %s document", file, doc.Doctype())
+}
+
+// run packs the document run by a step. Documents loaded from a file are
+// added to the graph, once per file, and referred to by their graph ID.
+func (p *packer) run(doc Document, parent string) (Document, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	file := sourceFile(doc)
+	if file == "" {
+		return p.doc(doc, parent)
+	}
+	if id, ok := p.byFile[file]; ok {
+		return DocumentRef{Location: "#" + id}, nil
+	}
+
+	id := p.uniqueID(file)
+	p.byFile[file] = id
+	d, err := p.doc(doc, file)
+	if err != nil {
+		return nil, err
+	}
+	setDocID(d, id)
+	p.graph.Docs = append(p.graph.Docs, d)
+	return DocumentRef{Location: "#" + id}, nil
+}
+
+// uniqueID returns a graph entry ID for a file, based on its name,
+// e.g. "wc-tool" for "tools/wc-tool.cwl".
+func (p *packer) uniqueID(file string) string {
+	base := path.Base(strings.Replace(file, "\\", "/", -1))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	id := base
+	for i := 2; p.ids[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	p.ids[id] = true
+	return id
+}
+
+// common checks the cwlVersion of a document, which is set once for the
+// whole graph, and moves "$namespaces" and "$schemas" to the graph.
+func (p *packer) common(version *string, ext *Extensions, file string) error {
+	if *version != "" {
+		if p.version == "" {
+			p.version = *version
+		} else if *version != p.version {
+			return errf("%s: can't pack documents with different versions, %s and %s",
+				file, p.version, *version)
+		}
+		*version = ""
+	}
+	if ext == nil || len(*ext) == 0 {
+		return nil
+	}
+
+	c := Extensions{}
+	for k, v := range *ext {
+		switch k {
+		case "$namespaces":
+			if err := p.namespaces(v, file); err != nil {
+				return err
+			}
+		case "$schemas":
+			p.schemas(v)
+		default:
+			c[k] = v
+		}
+	}
+	if len(c) == 0 {
+		c = nil
+	}
+	*ext = c
+	return nil
+}
+
+func (p *packer) namespaces(v interface{}, file string) error {
+	ns, ok := v.(map[string]interface{})
+	if !ok {
+		return errf("%s: $namespaces must be a mapping", file)
+	}
+	if p.graph.Extensions == nil {
+		p.graph.Extensions = Extensions{}
+	}
+	all, _ := p.graph.Extensions["$namespaces"].(map[string]interface{})
+	if all == nil {
+		all = map[string]interface{}{}
+		p.graph.Extensions["$namespaces"] = all
+	}
+	for prefix, uri := range ns {
+		if x, ok := all[prefix]; ok && x != uri {
+			return errf("%s: namespace %q is %v, but it's %v in another document",
+				file, prefix, uri, x)
+		}
+		all[prefix] = uri
+	}
+	return nil
+}
+
+func (p *packer) schemas(v interface{}) {
+	if p.graph.Extensions == nil {
+		p.graph.Extensions = Extensions{}
+	}
+	all, _ := p.graph.Extensions["$schemas"].([]interface{})
+	list, _ := v.([]interface{})
+Loop:
+	for _, s := range list {
+		for _, x := range all {
+			if reflect.DeepEqual(x, s) {
+				continue Loop
+			}
+		}
+		all = append(all, s)
+	}
+	p.graph.Extensions["$schemas"] = all
+}
+
+// packTypeRef rewrites a reference to a type in another file,
+// e.g. "types.yml#Sample", to "#Sample".
+func packTypeRef(t TypeRef) TypeRef {
+	if i := strings.LastIndex(t.Name, "#"); i > 0 {
+		return TypeRef{Name: t.Name[i:]}
+	}
+	return t
+}
+
+func packInputTypes(types []InputType) []InputType {
+	if types == nil {
+		return nil
+	}
+	out := make([]InputType, len(types))
+	for i, t := range types {
+		switch z := t.(type) {
+		case TypeRef:
+			t = packTypeRef(z)
+		case InputArray:
+			z.Items = packInputTypes(z.Items)
+			t = z
+		case InputRecord:
+			z.Fields = append([]InputField(nil), z.Fields...)
+			for j := range z.Fields {
+				z.Fields[j].Type = packInputTypes(z.Fields[j].Type)
+			}
+			t = z
+		}
+		out[i] = t
+	}
+	return out
+}
+
+func packOutputTypes(types []OutputType) []OutputType {
+	if types == nil {
+		return nil
+	}
+	out := make([]OutputType, len(types))
+	for i, t := range types {
+		switch z := t.(type) {
+		case TypeRef:
+			t = packTypeRef(z)
+		case OutputArray:
+			z.Items = packOutputTypes(z.Items)
+			t = z
+		case OutputRecord:
+			z.Fields = append([]OutputField(nil), z.Fields...)
+			for j := range z.Fields {
+				z.Fields[j].Type = packOutputTypes(z.Fields[j].Type)
+			}
+			t = z
+		}
+		out[i] = t
+	}
+	return out
+}
+
+func setDocID(doc Document, id string) {
+	switch z := doc.(type) {
+	case *Tool:
+		z.ID = id
+	case *Workflow:
+		z.ID = id
+	case *ExpressionTool:
+		z.ID = id
+	case *Operation:
+		z.ID = id
+	case *Script:
+		z.ID = id
+	}
+}
+
+// Unpack splits a packed document into separate documents, keyed by file
+// name, such as "main.cwl" for the "#main" entry. References to graph
+// entries, such as run: "#echo", are rewritten to the file names, and each
+// document gets the cwlVersion, "$namespaces" and "$schemas" of the graph.
+//
+// The graph should be loaded with NoResolve, so that its references are kept.
+// The documents are copied, so "g" isn't changed. IDs which aren't a plain
+// file name, such as "../x", are an error, so that the documents can be
+// written to a directory safely.
+func Unpack(g Graph) (map[string]Document, error) {
+	files := map[string]string{}
+	for _, d := range g.Docs {
+		id := strings.TrimPrefix(docID(d), "#")
+		if id == "" {
+			return nil, errf("$graph document is missing an id")
+		}
+		name := id + ".cwl"
+		if strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") || !fs.ValidPath(name) {
+			return nil, errf("$graph document id %q can't be used as a file name", id)
+		}
+		files[id] = name
+	}
+
+	out := map[string]Document{}
+	for _, d := range g.Docs {
+		c, err := unpackDoc(d, files)
+		if err != nil {
+			return nil, err
+		}
+		version, ext := docFields(c)
+		if version != nil && *version == "" {
+			*version = g.CWLVersion
+		}
+		if ext != nil && len(g.Extensions) != 0 {
+			x := Extensions{}
+			for k, v := range g.Extensions {
+				x[k] = v
+			}
+			for k, v := range *ext {
+				x[k] = v
+			}
+			*ext = x
+		}
+		out[files[strings.TrimPrefix(docID(d), "#")]] = c
+	}
+	return out, nil
+}
+
+// unpackDoc copies a document, rewriting references to graph entries
+// to the files in "files", keyed by graph entry ID.
+func unpackDoc(doc Document, files map[string]string) (Document, error) {
+	switch z := doc.(type) {
+	case *Tool:
+		c := *z
+		return &c, nil
+	case *ExpressionTool:
+		c := *z
+		return &c, nil
+	case *Operation:
+		c := *z
+		return &c, nil
+	case *Script:
+		c := *z
+		return &c, nil
+	case *Workflow:
+		c := *z
+		c.Steps = append([]Step(nil), z.Steps...)
+		for i := range c.Steps {
+			step := &c.Steps[i]
+			switch run := step.Run.(type) {
+			case nil:
+			case DocumentRef:
+				if !strings.HasPrefix(run.Location, "#") {
+					continue
+				}
+				file, ok := files[strings.TrimPrefix(run.Location, "#")]
+				if !ok {
+					return nil, errf("step %q: no document with id %q in $graph", step.ID, run.Location)
+				}
+				step.Run = DocumentRef{Location: file}
+			default:
+				// A resolved graph entry, or an embedded document.
+				if file, ok := files[strings.TrimPrefix(docID(run), "#")]; ok {
+					step.Run = DocumentRef{Location: file}
+					continue
+				}
+				r, err := unpackDoc(run, files)
+				if err != nil {
+					return nil, err
+				}
+				step.Run = r
+			}
+		}
+		return &c, nil
+	}
+	return nil, errf("can't unpack a %s document", doc.Doctype())
+}
+
+// docFields returns pointers to the cwlVersion and Extensions
+// of a document, or nil if it doesn't have them.
+func docFields(doc Document) (*string, *Extensions) {
+	switch z := doc.(type) {
+	case *Tool:
+		return &z.CWLVersion, &z.Extensions
+	case *Workflow:
+		return &z.CWLVersion, &z.Extensions
+	case *ExpressionTool:
+		return &z.CWLVersion, &z.Extensions
+	case *Operation:
+		return &z.CWLVersion, &z.Extensions
+	case *Script:
+		return &z.CWLVersion, nil
+	}
+	return nil, nil
+}
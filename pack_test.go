@@ -0,0 +1,121 @@
+package cwl
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	path := "examples/023-count-lines1-wf/tool.cwl"
+	doc, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Pack(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.IDs(), []string{"main", "parseInt-tool", "wc-tool"}) {
+		t.Errorf("unexpected graph IDs: %v", g.IDs())
+	}
+	if doc.(*Workflow).ID != "" {
+		t.Error("expected the original document to be unchanged")
+	}
+
+	b, err := MarshalYAML(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The packed document loads back to an equivalent tree.
+	packed, err := LoadDocumentBytes(b, filepath.Dir(path), DefaultResolver{})
+	if err != nil {
+		t.Fatalf("loading packed document: %s\n%s", err, b)
+	}
+	stripOrigins(doc)
+	stripOrigins(packed)
+	if !reflect.DeepEqual(doc, packed) {
+		b1, _ := json.Marshal(doc)
+		b2, _ := json.Marshal(packed)
+		t.Errorf("packed document isn't equivalent:\n%s\n%s", b1, b2)
+	}
+
+	// Unpacking splits the graph back into files.
+	g2, err := LoadDocumentBytes(b, ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Unpack(g2.(Graph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := files["main.cwl"].(*Workflow)
+	if main.CWLVersion != "v1.0" || main.Steps[0].Run != (DocumentRef{Location: "wc-tool.cwl"}) {
+		t.Errorf("unexpected unpacked workflow: %#v", main)
+	}
+	if tool := files["wc-tool.cwl"].(*Tool); tool.CWLVersion != "v1.0" {
+		t.Errorf("unexpected unpacked tool: %#v", tool)
+	}
+}
+
+func TestPackNamespaces(t *testing.T) {
+	doc := &Workflow{
+		CWLVersion: "v1.0",
+		Extensions: Extensions{"$namespaces": map[string]interface{}{"s": "https://schema.org/"}},
+		Steps: []Step{{
+			ID: "a",
+			Run: &Tool{
+				CWLVersion: "v1.0",
+				SourceFile: "tools/a.cwl",
+				Extensions: Extensions{
+					"$namespaces": map[string]interface{}{"edam": "http://edamontology.org/"},
+					"s:author":    "Jane Doe",
+				},
+			},
+		}},
+	}
+	g, err := Pack(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := map[string]interface{}{"s": "https://schema.org/", "edam": "http://edamontology.org/"}
+	if !reflect.DeepEqual(g.Extensions["$namespaces"], ns) {
+		t.Errorf("unexpected namespaces: %v", g.Extensions)
+	}
+	a, _ := g.Entry("a")
+	if ext := a.(*Tool).Extensions; !reflect.DeepEqual(ext, Extensions{"s:author": "Jane Doe"}) {
+		t.Errorf("unexpected tool extensions: %v", ext)
+	}
+
+	doc.Steps[0].Run.(*Tool).CWLVersion = "v1.2"
+	if _, err := Pack(doc); err == nil {
+		t.Error("expected error packing different versions")
+	}
+}
+
+// stripOrigins clears the fields which say where a document came from,
+// which differ between a packed document and the original files.
+func stripOrigins(doc Document) {
+	switch z := doc.(type) {
+	case *Workflow:
+		z.ID, z.SourceFile, z.CWLVersion = "", "", ""
+		for _, step := range z.Steps {
+			stripOrigins(step.Run)
+		}
+	case *Tool:
+		z.ID, z.SourceFile, z.CWLVersion = "", "", ""
+	case *ExpressionTool:
+		z.ID, z.SourceFile, z.CWLVersion = "", "", ""
+	}
+}
+
+func TestUnpackUnsafeIDs(t *testing.T) {
+	for _, id := range []string{"../../etc/x", "a/b", `a\b`, ".."} {
+		g := Graph{CWLVersion: "v1.0", Docs: []Document{&Tool{ID: id}}}
+		if _, err := Unpack(g); err == nil {
+			t.Errorf("expected an error for id %q", id)
+		}
+	}
+}
@@ -4,54 +4,39 @@ import (
 	"github.com/commondream/yamlast"
 )
 
+// preprocess handles the $import, $include and $mixin directives,
+// returning the processed tree. Imported documents are processed
+// recursively, relative to their own location.
+//
+// https://www.commonwl.org/v1.0/SchemaSalad.html#Document_preprocessing
 func (l *loader) preprocess(n node) (node, error) {
 	switch n.Kind {
 
 	case yamlast.MappingNode:
+		// With NoResolve(), directives are left in place.
+		_, noResolve := l.resolver.(noResolver)
+
+		if v, ok := directive(n, "$import"); ok && !noResolve {
+			return l.preprocessImport(n, v)
+		}
+		if v, ok := directive(n, "$include"); ok && !noResolve {
+			return l.preprocessInclude(n, v)
+		}
+
 		for i := 0; i < len(n.Children)-1; i += 2 {
 			k := n.Children[i]
-			v := n.Children[i+1]
-			switch k.Value {
-			case "$import":
-	      if _, ok := l.resolver.(noResolver); ok {
-          return n, nil
-        }
-				b, _, err := l.resolver.Resolve(l.base, v.Value)
-				if err != nil {
-					return nil, err
-				}
-				yamlnode, err := yamlast.Parse(b)
-				if err != nil {
-					return nil, err
-				}
-				// TODO set line/col/file of the new nodes
-				return yamlnode.Children[0], nil
-
-			case "$include":
-	      if _, ok := l.resolver.(noResolver); ok {
-          return n, nil
-        }
-				b, _, err := l.resolver.Resolve(l.base, v.Value)
-				if err != nil {
-					return nil, err
-				}
-				// TODO check line/col of the new node is correct
-				return node(&yamlast.Node{
-					Kind:   yamlast.ScalarNode,
-					Line:   n.Line,
-					Column: n.Column,
-					Value:  string(b),
-				}), nil
-
-			// TODO $mixin
-
-			default:
-				x, err := l.preprocess(v)
-				if err != nil {
-					return nil, err
-				}
-				n.Children[i+1] = x
+			if k.Value == "$mixin" {
+				continue
 			}
+			x, err := l.preprocess(n.Children[i+1])
+			if err != nil {
+				return nil, err
+			}
+			n.Children[i+1] = x
+		}
+
+		if v, ok := directive(n, "$mixin"); ok && !noResolve {
+			return l.preprocessMixin(n, v)
 		}
 
 	case yamlast.SequenceNode:
@@ -65,3 +50,183 @@ func (l *loader) preprocess(n node) (node, error) {
 	}
 	return n, nil
 }
+
+// preprocessImport replaces the mapping with the imported document.
+func (l *loader) preprocessImport(n, v node) (node, error) {
+	if len(n.Children) != 2 {
+		return nil, l.errorAt(n, "$import must be the only field")
+	}
+
+	imported, loc, base, err := l.resolveDirective("$import", v)
+	if err != nil {
+		return nil, err
+	}
+	return l.preprocessFile(imported, loc, base)
+}
+
+// preprocessInclude replaces the mapping with the contents of the
+// included file, as a string.
+func (l *loader) preprocessInclude(n, v node) (node, error) {
+	if len(n.Children) != 2 {
+		return nil, l.errorAt(n, "$include must be the only field")
+	}
+	if v.Kind != yamlast.ScalarNode {
+		return nil, l.errorAt(v, "$include must be a string")
+	}
+
+	b, _, err := l.resolver.Resolve(l.base, v.Value)
+	if err != nil {
+		return nil, l.errorAt(v, "failed to resolve $include: %s", err)
+	}
+	return node(&yamlast.Node{
+		Kind:   yamlast.ScalarNode,
+		Line:   n.Line,
+		Column: n.Column,
+		Value:  string(b),
+	}), nil
+}
+
+// preprocessMixin merges the fields of the mixin document into the
+// mapping. Fields of the mapping take precedence over the mixin.
+func (l *loader) preprocessMixin(n, v node) (node, error) {
+	mixin, loc, base, err := l.resolveDirective("$mixin", v)
+	if err != nil {
+		return nil, err
+	}
+	mixin, err = l.preprocessFile(mixin, loc, base)
+	if err != nil {
+		return nil, err
+	}
+	if mixin.Kind != yamlast.MappingNode {
+		return nil, l.errorAt(v, "$mixin of %s: expected a mapping", loc)
+	}
+
+	explicit := map[string]bool{}
+	for i := 0; i < len(n.Children)-1; i += 2 {
+		explicit[n.Children[i].Value] = true
+	}
+
+	var children []*yamlast.Node
+	for i := 0; i < len(mixin.Children)-1; i += 2 {
+		k := mixin.Children[i]
+		x := mixin.Children[i+1]
+		if explicit[k.Value] {
+			continue
+		}
+		// The mixin's root node is dropped,
+		// so each field needs to remember where it came from.
+		l.setOrigin(k, loc, base)
+		l.setOrigin(x, loc, base)
+		children = append(children, k, x)
+	}
+	for i := 0; i < len(n.Children)-1; i += 2 {
+		if n.Children[i].Value != "$mixin" {
+			children = append(children, n.Children[i], n.Children[i+1])
+		}
+	}
+	n.Children = children
+	return n, nil
+}
+
+// resolveDirective resolves and parses the document referenced by
+// a directive, returning its root node, location and base.
+func (l *loader) resolveDirective(name string, v node) (node, string, string, error) {
+	if v.Kind != yamlast.ScalarNode {
+		return nil, "", "", l.errorAt(v, "%s must be a string", name)
+	}
+
+	loc := docLocation(l.base, v.Value)
+	if err := l.checkCycle(v, name, loc); err != nil {
+		return nil, "", "", err
+	}
+
+	b, base, err := l.resolver.Resolve(l.base, v.Value)
+	if err != nil {
+		return nil, "", "", l.errorAt(v, "failed to resolve %s: %s", name, err)
+	}
+	roots, err := parseYAML(b, loc)
+	if err != nil {
+		return nil, "", "", l.wrapErr(v, err)
+	}
+	if len(roots) != 1 {
+		return nil, "", "", l.errorAt(v, "%s of %s: expected one YAML document", name, loc)
+	}
+	return roots[0], loc, base, nil
+}
+
+// preprocessFile preprocesses the root node of a document loaded from
+// "loc", resolving its relative references against "base".
+func (l *loader) preprocessFile(n node, loc, base string) (node, error) {
+	// Remember where the nodes came from, so that errors
+	// point into the right file and relative references
+	// found later, such as "run", use the right base.
+	l.setOrigin(n, loc, base)
+
+	outer := l.base
+	l.base = base
+	l.stack = append(l.stack, loc)
+	l.pending = append(l.pending, n)
+	defer func() {
+		l.base = outer
+		l.stack = l.stack[:len(l.stack)-1]
+		l.pending = l.pending[:len(l.pending)-1]
+	}()
+
+	x, err := l.preprocess(n)
+	if err != nil {
+		return nil, err
+	}
+	if x != n {
+		// The root was replaced by a nested import.
+		if _, ok := l.origins[x]; !ok {
+			l.setOrigin(x, loc, base)
+		}
+	}
+	return x, nil
+}
+
+// CheckDirectives returns an error positioned at the first $import,
+// $include or $mixin directive in the YAML document "b", if any.
+// NoResolve() leaves directives unresolved, and they're lost when the
+// loaded document is marshaled, so documents which are written back,
+// e.g. by an upgrade, can be checked first. "file" is used in errors.
+func CheckDirectives(b []byte, file string) error {
+	roots, err := parseYAML(b, file)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if n, name := findDirective(root); n != nil {
+			l := loader{file: file, resolver: NoResolve(), root: root}
+			return l.errorAt(n, "unresolved %s directive", name)
+		}
+	}
+	return nil
+}
+
+// findDirective returns the first mapping with a directive, and its name.
+func findDirective(n node) (node, string) {
+	if n.Kind == yamlast.MappingNode {
+		for _, name := range []string{"$import", "$include", "$mixin"} {
+			if _, ok := directive(n, name); ok {
+				return n, name
+			}
+		}
+	}
+	for _, c := range n.Children {
+		if x, name := findDirective(c); x != nil {
+			return x, name
+		}
+	}
+	return nil, ""
+}
+
+// directive finds the value of a directive, such as "$import", in a mapping.
+func directive(n node, name string) (node, bool) {
+	for i := 0; i < len(n.Children)-1; i += 2 {
+		if n.Children[i].Value == name {
+			return n.Children[i+1], true
+		}
+	}
+	return nil, false
+}
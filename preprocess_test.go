@@ -0,0 +1,149 @@
+package cwl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "cwl-preprocess-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPreprocessNested(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"wf.cwl": `
+cwlVersion: v1.0
+class: Workflow
+inputs:
+  $import: parts/inputs.yml
+outputs: []
+steps:
+  a:
+    $mixin: parts/step.yml
+    in: []
+`,
+		// Nested imports are relative to the importing file.
+		"parts/inputs.yml": `
+x:
+  $import: types/x.yml
+`,
+		"parts/types/x.yml": `
+type: string
+doc:
+  $include: ../doc.txt
+`,
+		"parts/doc.txt": "the x input",
+		"parts/step.yml": `
+run: tool.cwl
+in:
+  - id: overridden
+out: []
+`,
+		"parts/tool.cwl": `
+cwlVersion: v1.0
+class: CommandLineTool
+inputs: []
+outputs: []
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	doc, err := Load(filepath.Join(dir, "wf.cwl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := doc.(*Workflow)
+
+	if len(wf.Inputs) != 1 || wf.Inputs[0].ID != "x" || wf.Inputs[0].Doc != "the x input" {
+		t.Errorf("unexpected inputs: %#v", wf.Inputs)
+	}
+	if len(wf.Steps) != 1 {
+		t.Fatalf("unexpected steps: %#v", wf.Steps)
+	}
+	step := wf.Steps[0]
+	if len(step.In) != 0 {
+		t.Errorf("expected step inputs to override the mixin, got %#v", step.In)
+	}
+	if _, ok := step.Run.(*Tool); !ok {
+		t.Errorf("expected step to run a tool, got %#v", step.Run)
+	}
+}
+
+func TestPreprocessCycles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"import.cwl": `
+cwlVersion: v1.0
+class: Workflow
+inputs:
+  $import: a.yml
+outputs: []
+steps: []
+`,
+		"a.yml": "$import: b.yml\n",
+		"b.yml": "$import: a.yml\n",
+		"run.cwl": `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  again:
+    run: run.cwl
+    in: []
+    out: []
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := Load(filepath.Join(dir, "import.cwl"))
+	if err == nil || !strings.Contains(err.Error(), "$import cycle") {
+		t.Errorf("expected import cycle error, got %v", err)
+	}
+
+	_, err = Load(filepath.Join(dir, "run.cwl"))
+	if err == nil || !strings.Contains(err.Error(), "run cycle") {
+		t.Errorf("expected run cycle error, got %v", err)
+	}
+}
+
+func TestCheckDirectives(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: CommandLineTool
+requirements:
+  - class: InlineJavascriptRequirement
+    expressionLib:
+      - $include: lib.js
+inputs: []
+outputs: []
+`
+	err := CheckDirectives([]byte(doc), "tool.cwl")
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.Line != 7 || e.Path != "requirements.0.expressionLib.0" || !strings.Contains(e.Error(), "$include") {
+		t.Errorf("unexpected error: %s", e)
+	}
+
+	doc = strings.Replace(doc, "$include: lib.js", "var x = 1;", 1)
+	if err := CheckDirectives([]byte(doc), "tool.cwl"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
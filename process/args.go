@@ -3,32 +3,121 @@ package process
 import (
 	"fmt"
 	"cwl"
+	"cwl/expr"
+	"regexp"
 	"sort"
 	"strings"
 )
 
 /*** CWL tool command line argument building code ***/
 
+// Command binds the tool's base command, inputs and arguments
+// into a command line.
 func (process *Process) Command() ([]string, error) {
 
 	// Copy "Tool.Inputs" bindings
-	args := make([]*Binding, 0, len(process.bindings))
+	inputs := make([]*Binding, 0, len(process.bindings))
 	for _, b := range process.bindings {
 		if b.clb != nil {
-			args = append(args, b)
+			inputs = append(inputs, b)
 		}
 	}
 
+	args, err := process.bindArguments(inputs, process.tool.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	//debug(args)
+
+	cmd := process.buildCommand(process.tool.BaseCommand, args)
+	//debug("COMMAND", cmd)
+	return cmd, nil
+}
+
+// StepCommands binds each of the tool's CSteps into a command line,
+// returning one argv list per step, in order. Each step's BaseCommand
+// and Arguments, along with the bindings of the tool inputs the step
+// lists in CStep.Inputs, are bound and sorted the same as Command().
+func (process *Process) StepCommands() ([][]string, error) {
+	cmds := make([][]string, 0, len(process.tool.CSteps))
+	for i, step := range process.tool.CSteps {
+		args, err := process.stepArguments(step)
+		if err != nil {
+			return nil, errf("step %d: %s", i, err)
+		}
+		cmds = append(cmds, process.buildCommand(step.BaseCommand, args))
+	}
+	return cmds, nil
+}
+
+// MultiCommands binds each of the tool's CSteps into a single
+// shell command line. Prefer StepCommands(), which keeps
+// argument boundaries intact.
+func (process *Process) MultiCommands() ([]string, error) {
+	cmds := make([]string, 0, len(process.tool.CSteps))
+	for i, step := range process.tool.CSteps {
+		args, err := process.stepArguments(step)
+		if err != nil {
+			return nil, errf("step %d: %s", i, err)
+		}
+		// Each step is returned as a single shell command line,
+		// so arguments are always quoted unless "shellQuote: false".
+		cmds = append(cmds, shellLine(step.BaseCommand, args))
+	}
+	return cmds, nil
+}
+
+// stepArguments collects the input bindings referenced by a CStep
+// and binds them along with the step's arguments.
+func (process *Process) stepArguments(step cwl.CStep) ([]*Binding, error) {
+	var inputs []*Binding
+	for _, id := range step.Inputs {
+		found := false
+		for _, b := range process.bindings {
+			if b.name == id {
+				found = true
+				if b.clb != nil {
+					inputs = append(inputs, b)
+				}
+			}
+		}
+		if !found {
+			return nil, errf("unknown input %q", id)
+		}
+	}
+	return process.bindArguments(inputs, step.Arguments)
+}
+
+// bindArguments combines input bindings with "arguments" bindings,
+// evaluates "valueFrom" expressions and sorts the result.
+//
+// The given input bindings are copied, so that evaluating "valueFrom"
+// doesn't overwrite the input values seen by other expressions.
+func (process *Process) bindArguments(inputs []*Binding, arguments []*cwl.CommandLineBinding) ([]*Binding, error) {
+
+	args := make([]*Binding, 0, len(inputs)+len(arguments))
+	for _, b := range inputs {
+		c := *b
+		args = append(args, &c)
+	}
+
 	// Add "Tool.Arguments"
-	for i, arg := range process.tool.Arguments {
+	for i, arg := range arguments {
 		if arg.ValueFrom == "" {
 			return nil, errf("valueFrom is required but missing for argument %d", i)
 		}
 		args = append(args, &Binding{
-			arg, argType{}, nil, sortKey{arg.Position}, nil, "",
+			arg, argType{}, nil, sortKey{getPos(arg)}, nil, "",
 		})
 	}
 
+	// Evaluate expression positions, before "valueFrom" changes the value.
+	for _, b := range args {
+		if err := process.evalSortKey(b); err != nil {
+			return nil, err
+		}
+	}
+
 	// Evaluate "valueFrom" expression.
 	for _, b := range args {
 		if b.clb.GetValueFrom() != "" {
@@ -41,77 +130,64 @@ func (process *Process) Command() ([]string, error) {
 	}
 
 	sort.Stable(bySortKey(args))
-	//debug(args)
+	return args, nil
+}
 
-	// Now collect the input bindings into command line arguments
-	cmd := append([]string{}, process.tool.BaseCommand...)
-	for _, b := range args {
-		cmd = append(cmd, bindArgs(b)...)
-	}
+// buildCommand collects the base command and bound arguments
+// into command line arguments.
+func (process *Process) buildCommand(base []string, args []*Binding) []string {
 
+	// cwl spec:
+	// "shellQuote: If ShellCommandRequirement is in the requirements for
+	// the current command, this controls whether the value is quoted on
+	// the command line (default is true)."
 	if process.tool.RequiresShellCommand() {
-		cmd = []string{"/bin/sh", "-c", strings.Join(cmd, " ")}
+		return []string{"/bin/sh", "-c", shellLine(base, args)}
 	}
 
-	//debug("COMMAND", cmd)
-	return cmd, nil
+	cmd := append([]string{}, base...)
+	for _, b := range args {
+		cmd = append(cmd, bindArgs(b)...)
+	}
+	return cmd
 }
 
-func (process *Process) MultiCommands() ([]string, error) {
-
-	/*
-	// Copy "Tool.Inputs" bindings
-	args := make([]*Binding, 0, len(process.bindings))
-	for _, b := range process.bindings {
-		if b.clb != nil {
-			args = append(args, b)
-		}
+// shellLine joins the base command and bound arguments into a single
+// command line suitable for "/bin/sh -c". Every word is quoted, except
+// the arguments of bindings which set "shellQuote: false", which are
+// passed to the shell raw.
+func shellLine(base []string, args []*Binding) string {
+	var words []string
+	for _, s := range base {
+		words = append(words, shellQuote(s))
 	}
-	*/
-	
-	cmds := make([]string, 0)
-
-	// Add "Tool.Arguments"
-	for _, step := range process.tool.CSteps {
-		args := make([]*Binding, 0)
-		for i, arg := range step.Arguments {
-			if arg.ValueFrom == "" {
-				return nil, errf("valueFrom is required but missing for argument %d", i)
-			}
-			args = append(args, &Binding{
-				arg, argType{}, nil, sortKey{arg.Position}, nil, "",
-			})
-		}
-
-		// Evaluate "valueFrom" expression.
-		for _, b := range args {
-			if b.clb.GetValueFrom() != "" {
-				val, err := process.eval(b.clb.GetValueFrom(), b.Value)
-				if err != nil {
-					return nil, errf("failed to eval argument value: %s", err)
-				}
-				b.Value = val
+	for _, b := range args {
+		quote := b.clb == nil || b.clb.ShellQuote.Value()
+		for _, s := range bindArgs(b) {
+			if quote {
+				s = shellQuote(s)
 			}
+			words = append(words, s)
 		}
+	}
+	return strings.Join(words, " ")
+}
 
-		sort.Stable(bySortKey(args))
-		//debug(args)
-
-		// Now collect the input bindings into command line arguments
-		cmd := append([]string{}, step.BaseCommand...)
-		for _, b := range args {
-			cmd = append(cmd, bindArgs(b)...)
-		}
-
-		if process.tool.RequiresShellCommand() {
-			cmd = []string{"/bin/sh", "-c", strings.Join(cmd, " ")}
-		}
+// safeShellRX matches strings which don't need quoting in a POSIX shell.
+var safeShellRX = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
 
-		cmds = append(cmds, strings.Join(cmd, " "))
+// shellQuote quotes a string so that a POSIX shell treats it as a single
+// word with no expansion of variables, globs, redirects, etc.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
 	}
-	//debug("COMMAND", cmd)
-	return cmds, nil
+	if safeShellRX.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
 }
+
 // args converts a binding into a list of formatted command line arguments.
 func bindArgs(b *Binding) []string {
 	switch b.Type.(type) {
@@ -218,6 +294,50 @@ func valueToStrings(v cwl.Value) []string {
 
 type sortKey []interface{}
 
+// evalSortKey evaluates the positions in a binding's sort key
+// which are expressions, with the input value as "self".
+//
+// cwl spec (v1.1):
+// "If a CWL Parameter Reference or CWL Expression is used and if the
+// inputBinding is associated with an input parameter, then the value of
+// self will be the value of the input parameter ... Expressions must
+// return a single value of type int or a null."
+func (process *Process) evalSortKey(b *Binding) error {
+	var key sortKey
+	for _, k := range b.sortKey {
+		x, ok := k.(cwl.Expression)
+		if !ok {
+			key = append(key, k)
+			continue
+		}
+		if !expr.IsExpression(x) {
+			return errf("position must be an int or an expression, got %q", x)
+		}
+
+		val, err := process.eval(x, b.Value)
+		if err != nil {
+			return errf("failed to evaluate position: %s", err)
+		}
+		switch z := val.(type) {
+		case nil:
+			key = append(key, 0)
+		case int:
+			key = append(key, z)
+		case int64:
+			key = append(key, int(z))
+		case float64:
+			if z != float64(int(z)) {
+				return errf("position must evaluate to an int, got %v", z)
+			}
+			key = append(key, int(z))
+		default:
+			return errf("position must evaluate to an int, got %v", val)
+		}
+	}
+	b.sortKey = key
+	return nil
+}
+
 // bySortKey defines the rules for sorting bindings;
 // http://www.commonwl.org/v1.0/CommandLineTool.html#Input_binding
 type bySortKey []*Binding
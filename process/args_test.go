@@ -0,0 +1,85 @@
+package process
+
+import (
+	"cwl"
+	"reflect"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"", "''"},
+		{"foo", "foo"},
+		{"/path/to/file.txt", "/path/to/file.txt"},
+		{"foo 1>&2", "'foo 1>&2'"},
+		{"$HOME", "'$HOME'"},
+		{"it's", `'it'\''s'`},
+		{"sample name.bam", "'sample name.bam'"},
+	}
+
+	for _, test := range tests {
+		res := shellQuote(test.input)
+		if res != test.expect {
+			t.Errorf("shellQuote(%q): expected %s, got %s", test.input, test.expect, res)
+		}
+	}
+}
+
+func TestShellLine(t *testing.T) {
+	raw := &cwl.CommandLineBinding{}
+	raw.ShellQuote.Set(false)
+
+	args := []*Binding{
+		{nil, argType{}, "foo 1>&2", nil, nil, ""},
+		{raw, argType{}, "| wc -l", nil, nil, ""},
+	}
+
+	res := shellLine([]string{"echo"}, args)
+	expect := "echo 'foo 1>&2' | wc -l"
+	if res != expect {
+		t.Errorf("expected %s, got %s", expect, res)
+	}
+}
+
+func TestStepCommands(t *testing.T) {
+	tool := &cwl.Tool{
+		CSteps: []cwl.CStep{
+			{
+				BaseCommand: []string{"sort"},
+				Inputs:      []string{"reads"},
+				Arguments: []*cwl.CommandLineBinding{
+					{ValueFrom: "-r", Position: "0"},
+				},
+			},
+			{
+				BaseCommand: []string{"wc", "-l"},
+			},
+		},
+	}
+	proc := &Process{
+		tool: tool,
+		bindings: []*Binding{
+			{&cwl.CommandLineBinding{Position: "1"}, cwl.String{}, "my reads.txt", sortKey{1}, nil, "reads"},
+		},
+	}
+
+	cmds, err := proc.StepCommands()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{
+		{"sort", "-r", "my reads.txt"},
+		{"wc", "-l"},
+	}
+	if !reflect.DeepEqual(cmds, expect) {
+		t.Errorf("expected %q, got %q", expect, cmds)
+	}
+
+	tool.CSteps[1].Inputs = []string{"missing"}
+	if _, err := proc.StepCommands(); err == nil {
+		t.Error("expected error for unknown step input")
+	}
+}
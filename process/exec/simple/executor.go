@@ -0,0 +1,399 @@
+package simple
+
+import (
+	"cwl"
+	"cwl/process"
+	"cwl/process/fs/local"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Executor runs CommandLineTools as local processes, each in its own
+// working directory under Outdir. Executor implements process.Executor
+// and process.StreamExecutor.
+type Executor struct {
+	// Outdir is the directory where each job's working directory is created.
+	Outdir string
+	// InputsDir is the directory relative input file locations are resolved against.
+	InputsDir string
+	// StreamInputs stages streamable File inputs as named pipes fed from
+	// the file's location, instead of passing the file's path directly.
+	StreamInputs bool
+	// Timeout is the time limit of tools without a ToolTimeLimit requirement.
+	// Zero means no limit.
+	Timeout time.Duration
+}
+
+func (e *Executor) Execute(doc cwl.Document, vals cwl.Values) (cwl.Values, error) {
+	tool, ok := doc.(*cwl.Tool)
+	if !ok {
+		return nil, fmt.Errorf(`can't execute document type "%s"`, doc.Doctype())
+	}
+
+	j, err := e.prepare(tool, vals)
+	if err != nil {
+		return nil, err
+	}
+	defer j.close()
+
+	if err := j.start(); err != nil {
+		return nil, err
+	}
+	if err := j.wait(); err != nil {
+		return nil, err
+	}
+	// A failed copy into a pipe means the tool didn't see all of its input.
+	if err := j.close(); err != nil {
+		return nil, err
+	}
+	return j.outputs()
+}
+
+// ExecuteStream runs two tools concurrently, connected by a named pipe
+// which replaces the first tool's streamable output file and is passed
+// to the second tool as its streamable input.
+func (e *Executor) ExecuteStream(link process.StreamLink, from cwl.Document, fromVals cwl.Values,
+	to cwl.Document, toVals cwl.Values) (cwl.Values, cwl.Values, error) {
+
+	fromTool, ok := from.(*cwl.Tool)
+	if !ok {
+		return nil, nil, fmt.Errorf(`can't execute document type "%s"`, from.Doctype())
+	}
+	toTool, ok := to.(*cwl.Tool)
+	if !ok {
+		return nil, nil, fmt.Errorf(`can't execute document type "%s"`, to.Doctype())
+	}
+
+	producer, err := e.prepare(fromTool, fromVals)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer producer.close()
+
+	name, err := streamOutputPath(producer.proc, link.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+	fifo := filepath.Join(producer.dir, name)
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		return nil, nil, fmt.Errorf("creating named pipe for output %q: %s", link.Output, err)
+	}
+
+	consumerVals := cwl.Values{}
+	for k, v := range toVals {
+		consumerVals[k] = v
+	}
+	consumerVals[link.Input] = cwl.File{Location: fifo}
+
+	consumer, err := e.prepare(toTool, consumerVals)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer consumer.close()
+
+	producer.fifo = fifo
+	consumer.fifo = fifo
+	errs := runConnected(fifo, producer, consumer)
+	perr, cerr := errs[0], errs[1]
+	if perr != nil {
+		return nil, nil, fmt.Errorf("step %q: %w", link.From, perr)
+	}
+	if cerr != nil {
+		return nil, nil, fmt.Errorf("step %q: %w", link.To, cerr)
+	}
+	if err := producer.close(); err != nil {
+		return nil, nil, fmt.Errorf("step %q: %w", link.From, err)
+	}
+	if err := consumer.close(); err != nil {
+		return nil, nil, fmt.Errorf("step %q: %w", link.To, err)
+	}
+
+	fromOut, err := producer.outputs()
+	if err != nil {
+		return nil, nil, err
+	}
+	toOut, err := consumer.outputs()
+	if err != nil {
+		return nil, nil, err
+	}
+	return fromOut, toOut, nil
+}
+
+// runConnected runs jobs connected by the named pipe at "fifo", returning
+// the error of each job. Opening either end of a named pipe blocks until
+// the other end is opened, so the jobs must be started concurrently.
+// A job may exit, or fail to start, without opening its end, so when a
+// job is done, the pipe is opened until the other jobs have started.
+func runConnected(fifo string, jobs ...*job) []error {
+	errs := make([]error, len(jobs))
+
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j *job) {
+			defer wg.Done()
+			err := j.start()
+			if err == nil {
+				err = j.wait()
+			}
+			errs[i] = err
+
+			for k, other := range jobs {
+				if k != i {
+					releaseFIFO(fifo, other.started)
+				}
+			}
+		}(i, j)
+	}
+	wg.Wait()
+	return errs
+}
+
+// releaseFIFO opens the named pipe at "path" until "started" is closed,
+// releasing a job which is blocked opening the other end.
+func releaseFIFO(path string, started <-chan struct{}) {
+	for {
+		select {
+		case <-started:
+			return
+		default:
+		}
+		unblockFIFO(path)
+		select {
+		case <-started:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// job is a single run of a tool.
+type job struct {
+	tool    *cwl.Tool
+	proc    *process.Process
+	dir     string
+	cmd     *exec.Cmd
+	streams *Streams
+	files   []*os.File
+	// fifo is a named pipe which the job's standard streams may be
+	// connected to, released if the job times out while opening it.
+	fifo string
+	// limit is the job's wall-clock time limit, zero means no limit.
+	limit time.Duration
+	timer *time.Timer
+	// started is closed when start returns.
+	started chan struct{}
+
+	mtx      sync.Mutex
+	timedOut bool
+}
+
+func (e *Executor) prepare(tool *cwl.Tool, vals cwl.Values) (*job, error) {
+	outdir := e.Outdir
+	if outdir == "" {
+		outdir = "."
+	}
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %s", err)
+	}
+	dir, err := ioutil.TempDir(outdir, "job-")
+	if err != nil {
+		return nil, fmt.Errorf("creating job directory: %s", err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := process.Runtime{
+		Outdir: dir,
+		Tmpdir: os.TempDir(),
+	}
+	fs := local.NewLocal(e.InputsDir)
+
+	proc, err := process.NewProcess(tool, vals, rt, fs)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &job{tool: tool, proc: proc, dir: dir, limit: e.Timeout, started: make(chan struct{})}
+	if limit, ok := proc.TimeLimit(); ok {
+		j.limit = limit
+	}
+
+	if e.StreamInputs {
+		j.streams, err = StreamInputs(proc, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// start opens the job's standard streams and starts the command.
+func (j *job) start() error {
+	defer close(j.started)
+	// Opening a standard stream connected to a named pipe may block,
+	// so the time limit starts before the streams are opened.
+	if j.limit > 0 {
+		j.timer = time.AfterFunc(j.limit, j.timeout)
+	}
+	err := j.startCommand()
+	if err != nil && j.timer != nil {
+		j.timer.Stop()
+	}
+	return err
+}
+
+func (j *job) startCommand() error {
+	args, err := j.proc.Command()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = j.dir
+	// Run the command in its own process group, so that on timeout
+	// the whole group, including any children of a shell, is killed.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Output not captured by the tool is logged to stderr,
+	// to keep it separate from the output values.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	// TODO envExpr is not supported by the simple executor.
+	env, _ := j.proc.Env()
+	cmd.Env = append(os.Environ(), "HOME="+j.dir, "TMPDIR="+os.TempDir())
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	if p := j.proc.Stdin(); p != "" {
+		fh, err := os.Open(p)
+		if err != nil {
+			return fmt.Errorf("opening stdin file: %s", err)
+		}
+		j.files = append(j.files, fh)
+		cmd.Stdin = fh
+	}
+	if p := j.proc.Stdout(); p != "" {
+		fh, err := os.OpenFile(filepath.Join(j.dir, p), os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("opening stdout file: %s", err)
+		}
+		j.files = append(j.files, fh)
+		cmd.Stdout = fh
+	}
+	if p := j.proc.Stderr(); p != "" {
+		fh, err := os.Create(filepath.Join(j.dir, p))
+		if err != nil {
+			return fmt.Errorf("opening stderr file: %s", err)
+		}
+		j.files = append(j.files, fh)
+		cmd.Stderr = fh
+	}
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	if j.timedOut {
+		j.closeFiles()
+		return &process.TimeoutError{Limit: j.limit}
+	}
+	err = cmd.Start()
+	if err == nil {
+		j.cmd = cmd
+	}
+
+	// The child has its own copies of the standard streams. Closing ours
+	// lets the other end of a named pipe see EOF when the child exits.
+	j.closeFiles()
+	return err
+}
+
+// timeout kills the job's process group, or if the command hasn't
+// started yet, releases it if it's blocked opening the named pipe.
+func (j *job) timeout() {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	j.timedOut = true
+	if j.cmd != nil {
+		syscall.Kill(-j.cmd.Process.Pid, syscall.SIGKILL)
+	} else if j.fifo != "" {
+		go releaseFIFO(j.fifo, j.started)
+	}
+}
+
+// wait waits for the command to finish and checks its exit code.
+func (j *job) wait() error {
+	err := j.cmd.Wait()
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	j.mtx.Lock()
+	timedOut := j.timedOut
+	j.mtx.Unlock()
+	if timedOut {
+		return &process.TimeoutError{Limit: j.limit}
+	}
+
+	if e, ok := err.(*exec.ExitError); ok {
+		code := e.ExitCode()
+		for _, c := range j.tool.SuccessCodes {
+			if c == code {
+				return nil
+			}
+		}
+		return fmt.Errorf("command failed with exit code %d", code)
+	}
+	return err
+}
+
+func (j *job) outputs() (cwl.Values, error) {
+	outfs := local.NewLocal(j.dir)
+	return j.proc.Outputs(outfs)
+}
+
+func (j *job) closeFiles() {
+	for _, fh := range j.files {
+		fh.Close()
+	}
+	j.files = nil
+}
+
+// close releases the files and streams of the job. It may be called
+// more than once, and returns the first error encountered while streaming.
+func (j *job) close() error {
+	j.closeFiles()
+	if j.streams != nil {
+		s := j.streams
+		j.streams = nil
+		return s.Close()
+	}
+	return nil
+}
+
+// streamOutputPath returns the path, relative to the job directory,
+// where a tool writes a streamable output.
+func streamOutputPath(proc *process.Process, id string) (string, error) {
+	for _, out := range proc.Tool().Outputs {
+		if out.ID != id || len(out.Type) != 1 {
+			continue
+		}
+		if _, ok := out.Type[0].(cwl.Stdout); ok {
+			return proc.Stdout(), nil
+		}
+		if out.OutputBinding != nil && len(out.OutputBinding.Glob) == 1 {
+			return string(out.OutputBinding.Glob[0]), nil
+		}
+	}
+	return "", fmt.Errorf("output %q can't be streamed", id)
+}
@@ -0,0 +1,112 @@
+package simple
+
+import (
+	"cwl"
+	"cwl/process"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExecutorTimeLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-simple-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The shell's child must be killed too, or Wait() would block
+	// until the sleep finishes.
+	tool := &cwl.Tool{
+		BaseCommand: []string{"sh", "-c", "sleep 30; echo done"},
+		Requirements: []cwl.Requirement{
+			cwl.ToolTimeLimit{Timelimit: "$(0.2)"},
+		},
+	}
+	e := &Executor{Outdir: dir}
+
+	start := time.Now()
+	_, err = e.Execute(tool, cwl.Values{})
+	if !process.IsTimeout(err) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("job was not killed in time, took %s", d)
+	}
+
+	// Without a ToolTimeLimit, the executor's default applies.
+	tool.Requirements = nil
+	e.Timeout = 200 * time.Millisecond
+	_, err = e.Execute(tool, cwl.Values{})
+	if !process.IsTimeout(err) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+
+	// A zero ToolTimeLimit means no limit, even with a default.
+	tool.BaseCommand = []string{"true"}
+	tool.Requirements = []cwl.Requirement{cwl.ToolTimeLimit{Timelimit: "0"}}
+	if _, err := e.Execute(tool, cwl.Values{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecuteStreamUnopened(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-simple-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stdoutProducer := &cwl.Tool{
+		BaseCommand: []string{"echo", "hello"},
+		Outputs:     []cwl.CommandOutput{{ID: "out", Type: []cwl.OutputType{cwl.Stdout{}}}},
+	}
+	// The producer exits without opening its output file.
+	globProducer := &cwl.Tool{
+		BaseCommand: []string{"true"},
+		Outputs: []cwl.CommandOutput{{
+			ID:            "out",
+			Streamable:    true,
+			Type:          []cwl.OutputType{cwl.FileType{}},
+			OutputBinding: &cwl.CommandOutputBinding{Glob: []cwl.Expression{"out.txt"}},
+		}},
+	}
+	in := []cwl.CommandInput{{ID: "in", Streamable: true, Type: []cwl.InputType{cwl.FileType{}}}}
+	// The consumer exits without reading its input.
+	argConsumer := &cwl.Tool{BaseCommand: []string{"true"}, Inputs: in}
+	stdinConsumer := &cwl.Tool{BaseCommand: []string{"cat"}, Inputs: in, Stdin: "$(inputs.in.path)"}
+
+	tests := []struct {
+		name     string
+		from, to *cwl.Tool
+	}{
+		{"consumer never reads", stdoutProducer, argConsumer},
+		{"producer never writes", globProducer, stdinConsumer},
+	}
+	for _, test := range tests {
+		e := &Executor{Outdir: dir}
+		link := process.StreamLink{From: "a", Output: "out", To: "b", Input: "in"}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			e.ExecuteStream(link, test.from, cwl.Values{}, test.to, cwl.Values{})
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: ExecuteStream didn't return", test.name)
+		}
+	}
+
+	// The time limit applies while the producer is blocked opening its output.
+	limited := *stdoutProducer
+	limited.Requirements = []cwl.Requirement{cwl.ToolTimeLimit{Timelimit: "$(0.2)"}}
+	sleeper := &cwl.Tool{BaseCommand: []string{"sleep", "2"}, Inputs: in}
+	e := &Executor{Outdir: dir}
+	link := process.StreamLink{From: "a", Output: "out", To: "b", Input: "in"}
+	_, _, err = e.ExecuteStream(link, &limited, cwl.Values{}, sleeper, cwl.Values{})
+	if !process.IsTimeout(err) {
+		t.Errorf("expected the producer to time out, got %v", err)
+	}
+}
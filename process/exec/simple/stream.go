@@ -0,0 +1,143 @@
+package simple
+
+import (
+	"cwl"
+	"cwl/process"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// Streams holds the named pipes created by StreamInputs.
+type Streams struct {
+	dir   string
+	owned bool
+	pipes []string
+	wg    sync.WaitGroup
+
+	mtx  sync.Mutex
+	errs []error
+}
+
+// StreamInputs stages the streamable File inputs of a process as named
+// pipes (FIFOs) created in "dir", each fed from the file's location by
+// a background goroutine. If "dir" is empty, a temporary directory is used.
+//
+// StreamInputs must be called before proc.Command(), because the
+// path of each streamed input changes to the path of its pipe.
+// The caller must call Streams.Close() after the command exits.
+func StreamInputs(proc *process.Process, dir string) (*Streams, error) {
+	s := &Streams{dir: dir}
+	if dir == "" {
+		d, err := ioutil.TempDir("", "cwl-stream-")
+		if err != nil {
+			return nil, fmt.Errorf("creating stream directory: %s", err)
+		}
+		s.dir = d
+		s.owned = true
+	}
+
+	for _, b := range proc.StreamableInputs() {
+		f := b.Value.(cwl.File)
+
+		// Keep the basename, for tools which care about the file extension.
+		path := filepath.Join(s.dir, b.Name(), f.Basename)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("creating stream directory: %s", err)
+		}
+		if err := syscall.Mkfifo(path, 0644); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("creating named pipe for input %q: %s", b.Name(), err)
+		}
+		s.pipes = append(s.pipes, path)
+
+		if err := proc.StageInput(b.Name(), path); err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		s.wg.Add(1)
+		go s.feed(path, f.Location)
+	}
+	return s, nil
+}
+
+// feed copies the file at "src" into the named pipe at "path".
+// Opening the pipe blocks until the command opens it for reading.
+func (s *Streams) feed(path, src string) {
+	defer s.wg.Done()
+
+	r, err := os.Open(src)
+	if err != nil {
+		s.fail(err)
+		// Open and close the pipe, so that the command sees EOF
+		// instead of blocking forever opening its input.
+		if w, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			w.Close()
+		}
+		return
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	defer w.Close()
+
+	// A command is allowed to exit without reading all of its input.
+	_, err = io.Copy(w, r)
+	if err != nil && !errors.Is(err, syscall.EPIPE) {
+		s.fail(fmt.Errorf("streaming %s: %s", src, err))
+	}
+}
+
+func (s *Streams) fail(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+// Close waits for the pipes to be drained and removes them.
+// Pipes which were never opened by the command are released.
+// Close returns the first error encountered while streaming.
+func (s *Streams) Close() error {
+	for _, p := range s.pipes {
+		unblockFIFO(p)
+	}
+	s.wg.Wait()
+
+	for _, p := range s.pipes {
+		os.Remove(p)
+	}
+	if s.owned {
+		os.RemoveAll(s.dir)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.errs) > 0 {
+		return s.errs[0]
+	}
+	return nil
+}
+
+// unblockFIFO releases anyone blocked opening the named pipe at "path",
+// by briefly opening both ends without blocking.
+func unblockFIFO(path string) {
+	r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err == nil {
+		defer r.Close()
+	}
+	w, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err == nil {
+		w.Close()
+	}
+}
@@ -109,7 +109,8 @@ func (process *Process) resolveFile(f cwl.File, loadContents bool) (cwl.File, er
 	return f, nil
 }
 
-func (process *Process) resolveSecondaryFiles(file cwl.File, x cwl.Expression) error {
+func (process *Process) resolveSecondaryFiles(file *cwl.File, sf cwl.SecondaryFile, output bool) error {
+	x := sf.Pattern
 
 	// cwl spec:
 	// "If the value is an expression, the value of self in the expression
@@ -148,6 +149,16 @@ func (process *Process) resolveSecondaryFiles(file cwl.File, x cwl.Expression) e
 		Location: location + pattern,
 	}
 
+	required, err := secondaryFileRequired(sf, output, process.eval, *file)
+	if err != nil {
+		return err
+	}
+	if !required {
+		if _, err := process.fs.Info(sec.Location); err == ErrFileNotFound {
+			return nil
+		}
+	}
+
 	// TODO does LoadContents apply to secondary files? not in the spec
 	f, err := process.resolveFile(sec, false)
 	if err != nil {
@@ -158,6 +169,36 @@ func (process *Process) resolveSecondaryFiles(file cwl.File, x cwl.Expression) e
 	return nil
 }
 
+// secondaryFileRequired returns true if a secondary file must exist.
+// "required" is a boolean or an expression. If it's not given,
+// the secondary files of inputs are required and those of outputs aren't.
+func secondaryFileRequired(
+	sf cwl.SecondaryFile,
+	output bool,
+	eval func(cwl.Expression, interface{}) (interface{}, error),
+	self cwl.File,
+) (bool, error) {
+
+	switch strings.TrimSpace(string(sf.Required)) {
+	case "":
+		return !output, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	v, err := eval(sf.Required, self)
+	if err != nil {
+		return false, errf("evaluating required for secondary file %q: %s", sf.Pattern, err)
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errf("required for secondary file %q must be a boolean, got %v", sf.Pattern, v)
+	}
+	return b, nil
+}
+
 // splitname splits a file name into root and extension,
 // with some special CWL rules.
 func splitname(n string) (root, ext string) {
@@ -0,0 +1,62 @@
+package process
+
+import (
+	"cwl"
+	"testing"
+)
+
+// mapFS is a Filesystem with only the files in the map.
+type mapFS map[string]string
+
+func (m mapFS) Create(path, contents string) (cwl.File, error) {
+	m[path] = contents
+	return m.Info(path)
+}
+
+func (m mapFS) Info(loc string) (cwl.File, error) {
+	c, ok := m[loc]
+	if !ok {
+		return cwl.File{}, ErrFileNotFound
+	}
+	return cwl.File{Location: loc, Path: loc, Size: int64(len(c))}, nil
+}
+
+func (m mapFS) Contents(loc string) (string, error) {
+	c, ok := m[loc]
+	if !ok {
+		return "", ErrFileNotFound
+	}
+	return c, nil
+}
+
+func (m mapFS) Glob(pattern string) ([]cwl.File, error) {
+	return nil, nil
+}
+
+func TestSecondaryFileRequired(t *testing.T) {
+	process := &Process{fs: mapFS{"reads.bam": "", "reads.bam.bai": ""}}
+
+	tests := []struct {
+		sf      cwl.SecondaryFile
+		output  bool
+		found   int
+		wantErr bool
+	}{
+		{cwl.SecondaryFile{Pattern: ".bai"}, false, 1, false},
+		{cwl.SecondaryFile{Pattern: ".crai"}, false, 0, true},
+		{cwl.SecondaryFile{Pattern: ".crai"}, true, 0, false},
+		{cwl.SecondaryFile{Pattern: ".crai", Required: "true"}, true, 0, true},
+		{cwl.SecondaryFile{Pattern: ".crai", Required: "false"}, false, 0, false},
+		{cwl.SecondaryFile{Pattern: ".bai", Required: "false"}, false, 1, false},
+	}
+	for _, test := range tests {
+		f := cwl.File{Location: "reads.bam"}
+		err := process.resolveSecondaryFiles(&f, test.sf, test.output)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%+v: unexpected error: %v", test.sf, err)
+		}
+		if len(f.SecondaryFiles) != test.found {
+			t.Errorf("%+v: expected %d secondary files, got %d", test.sf, test.found, len(f.SecondaryFiles))
+		}
+	}
+}
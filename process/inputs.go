@@ -49,7 +49,7 @@ func (process *Process) bindInput(
 	name string,
 	types []cwl.InputType,
 	clb *cwl.CommandLineBinding,
-	secondaryFiles []cwl.Expression,
+	secondaryFiles []cwl.SecondaryFile,
 	val interface{},
 	key sortKey,
 ) ([]*Binding, error) {
@@ -211,8 +211,10 @@ Loop:
 			}
 			// TODO figure out a good way to do this.
 			//f.Path = "/inputs/" + f.Path			
-			for _, expr := range secondaryFiles {
-				process.resolveSecondaryFiles(f, expr)
+			for _, sf := range secondaryFiles {
+				if err := process.resolveSecondaryFiles(&f, sf, false); err != nil {
+					return nil, errf("resolving secondary files: %s", err)
+				}
 			}
 
 			return []*Binding{
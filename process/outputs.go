@@ -35,7 +35,7 @@ func (process *Process) bindOutput(
 	fs Filesystem,
 	types []cwl.OutputType,
 	binding *cwl.CommandOutputBinding,
-	secondaryFiles []cwl.Expression,
+	secondaryFiles []cwl.SecondaryFile,
 	val interface{},
 ) (interface{}, error) {
 	var err error
@@ -146,8 +146,8 @@ Loop:
 					continue Loop
 				}
 				f := y[0]
-				for _, expr := range secondaryFiles {
-					err := process.resolveSecondaryFiles(f, expr)
+				for _, sf := range secondaryFiles {
+					err := process.resolveSecondaryFiles(&f, sf, true)
 					if err != nil {
 						return nil, errf("resolving secondary files: %s", err)
 					}
@@ -5,6 +5,10 @@ import (
 	"cwl"
 	"cwl/expr"
 	"github.com/rs/xid"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Mebibyte int
@@ -58,6 +62,8 @@ type Process struct {
 	stdin 		   string
 	stdout         string
 	stderr         string
+	timelimit      time.Duration
+	hasTimelimit   bool
 }
 
 func NewProcess(tool *cwl.Tool, values cwl.Values, rt Runtime, fs Filesystem) (*Process, error) {
@@ -171,6 +177,26 @@ func NewProcess(tool *cwl.Tool, values cwl.Values, rt Runtime, fs Filesystem) (*
 		files = append(files, flattenFiles(f)...)
 	  }
 	}
+
+	// The file named by "stdin" must be staged like any other input,
+	// even when it isn't referenced by an input binding.
+	if stdinStr != "" {
+		f, err := process.resolveFile(cwl.File{Location: stdinStr}, false)
+		if err != nil {
+			return nil, wrap(err, "resolving stdin file")
+		}
+		process.stdin = f.Path
+
+		staged := false
+		for _, x := range files {
+			if x.Location == f.Location {
+				staged = true
+			}
+		}
+		if !staged {
+			files = append(files, f)
+		}
+	}
 	process.inputfiles = files
 
 	return process, nil
@@ -184,6 +210,50 @@ func (process *Process) InputFiles() []string {
 	return files
 }
 
+// StreamableInputs returns the bindings of File inputs
+// which the tool marks as "streamable".
+func (process *Process) StreamableInputs() []*Binding {
+	var out []*Binding
+	for _, in := range process.tool.Inputs {
+		if !in.Streamable {
+			continue
+		}
+		for _, b := range process.bindings {
+			if _, ok := b.Value.(cwl.File); ok && b.name == in.ID {
+				out = append(out, b)
+			}
+		}
+	}
+	return out
+}
+
+// StageInput changes the path of the File bound to the named input,
+// for executors which stage the file somewhere other than its location,
+// such as a named pipe. StageInput must be called before Command().
+func (process *Process) StageInput(name, path string) error {
+	for _, b := range process.bindings {
+		f, ok := b.Value.(cwl.File)
+		if !ok || b.name != name {
+			continue
+		}
+		for i, x := range process.inputfiles {
+			if x.Location == f.Location {
+				process.inputfiles[i].Path = path
+			}
+		}
+		if process.stdin == f.Path {
+			process.stdin = path
+		}
+		f.Path = path
+		f.Dirname = filepath.Dir(path)
+		b.Value = f
+		return nil
+	}
+	return errf("no File input named %q", name)
+}
+
+// Stdin returns the path of the file which should be connected
+// to the command's standard input, or an empty string.
 func (process *Process) Stdin() string {
 	return process.stdin
 }
@@ -220,6 +290,13 @@ func (process *Process) Env() (map[string]string, []string) {
 	return env, envExpr
 }
 
+// TimeLimit returns the wall-clock limit set by a ToolTimeLimit requirement.
+// The boolean is false if the tool has no ToolTimeLimit. A zero limit means
+// the tool may run forever.
+func (process *Process) TimeLimit() (time.Duration, bool) {
+	return process.timelimit, process.hasTimelimit
+}
+
 func (process *Process) LRM() map[string]string {
 	lrm := map[string]string{}
 	for k, v := range process.lrm {
@@ -273,6 +350,14 @@ func (process *Process) loadReqs() error {
 		case cwl.ResourceRequirement:
 			// TODO eval expressions
 
+		case cwl.ToolTimeLimit:
+			limit, err := process.evalTimeLimit(z.Timelimit)
+			if err != nil {
+				return errf("failed to evaluate ToolTimeLimit: %s", err)
+			}
+			process.timelimit = limit
+			process.hasTimelimit = true
+
 		case cwl.SchemaDefRequirement:
 			return errf("SchemaDefRequirement is not supported (yet)")
 		case cwl.InitialWorkDirRequirement:
@@ -334,6 +419,43 @@ func (process *Process) evalExprArr(arr []cwl.Expression) ([]string, error) {
 	return r, nil
 }
 
+// evalTimeLimit evaluates a ToolTimeLimit "timelimit" field,
+// which is a number of seconds or an expression returning one.
+func (process *Process) evalTimeLimit(x cwl.Expression) (time.Duration, error) {
+	var val interface{} = string(x)
+	if expr.IsExpression(x) {
+		v, err := process.eval(x, nil)
+		if err != nil {
+			return 0, errf(`failed to evaluate expression: "%s": %s`, x, err)
+		}
+		val = v
+	}
+
+	var err error
+
+	var secs float64
+	switch z := val.(type) {
+	case int:
+		secs = float64(z)
+	case int64:
+		secs = float64(z)
+	case float64:
+		secs = z
+	case string:
+		secs, err = strconv.ParseFloat(strings.TrimSpace(z), 64)
+		if err != nil {
+			return 0, errf(`timelimit must be a number, got "%s"`, z)
+		}
+	default:
+		return 0, errf(`timelimit must be a number, got "%v"`, val)
+	}
+
+	if secs < 0 {
+		return 0, errf("timelimit must not be negative, got %v", secs)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
 func (process *Process) evalLRM(def map[string]cwl.Expression) error {
 	for k, expr := range def {
 		val, err := process.eval(expr, nil)
@@ -0,0 +1,546 @@
+package process
+
+import (
+	"cwl"
+	"cwl/expr"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+/*** CWL workflow execution code ***/
+
+// Executor executes a single workflow step document, such as a
+// CommandLineTool, with the given input values and returns its outputs.
+type Executor interface {
+	Execute(doc cwl.Document, vals cwl.Values) (cwl.Values, error)
+}
+
+// StreamExecutor is implemented by executors which can run two steps
+// concurrently, connecting a streamable output of the first step directly
+// to a streamable input of the second step. See StreamLinks().
+//
+// "toVals" is missing the value for "link.Input"; the executor provides it.
+type StreamExecutor interface {
+	ExecuteStream(link StreamLink, from cwl.Document, fromVals cwl.Values,
+		to cwl.Document, toVals cwl.Values) (fromOut, toOut cwl.Values, err error)
+}
+
+// TimeoutError is returned by an Executor when a job is killed
+// for running longer than its time limit.
+type TimeoutError struct {
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out: job exceeded its time limit of %s", e.Limit)
+}
+
+// IsTimeout returns true if the error, or an error it wraps, is a TimeoutError.
+func IsTimeout(err error) bool {
+	var te *TimeoutError
+	return errors.As(err, &te)
+}
+
+// WorkflowRunner runs the steps of a workflow in dependency order,
+// passing values between steps.
+type WorkflowRunner struct {
+	Executor Executor
+	// Stream enables connecting streamable outputs directly to the next step,
+	// if the Executor is also a StreamExecutor.
+	Stream bool
+}
+
+// Run runs the workflow with the given input values
+// and returns the workflow output values.
+func (r *WorkflowRunner) Run(wf *cwl.Workflow, vals cwl.Values) (cwl.Values, error) {
+	if r.Executor == nil {
+		return nil, errf("workflow runner has no executor")
+	}
+
+	// state holds every value produced so far, keyed by
+	// workflow input ID or "step/output" ID.
+	state := map[string]cwl.Value{}
+	for _, in := range wf.Inputs {
+		val, ok := vals[in.ID]
+		if !ok || val == nil {
+			val = in.Default
+		}
+		state[in.ID] = val
+	}
+
+	var links []StreamLink
+	if _, ok := r.Executor.(StreamExecutor); ok && r.Stream {
+		links = StreamLinks(wf)
+	}
+
+	done := map[string]bool{}
+	for len(done) < len(wf.Steps) {
+		ran := false
+
+		for i, step := range wf.Steps {
+			if done[step.ID] || !r.stepReady(step, state) {
+				continue
+			}
+
+			// Try to stream this step's output into the next step.
+			if link, ok := findStreamLink(links, step.ID); ok {
+				next := wf.Steps[i+1]
+				if !done[next.ID] && r.stepReady(next, state, link.Input) {
+					err := r.runStream(wf, link, step, next, state)
+					if err != nil {
+						return nil, err
+					}
+					done[step.ID] = true
+					done[next.ID] = true
+					ran = true
+					continue
+				}
+			}
+
+			err := r.runStep(wf, step, state)
+			if err != nil {
+				return nil, err
+			}
+			done[step.ID] = true
+			ran = true
+		}
+
+		if !ran {
+			var blocked []string
+			for _, step := range wf.Steps {
+				if !done[step.ID] {
+					blocked = append(blocked, step.ID)
+				}
+			}
+			return nil, errf("workflow steps can't run, missing sources: %s",
+				strings.Join(blocked, ", "))
+		}
+	}
+
+	out := cwl.Values{}
+	for _, o := range wf.Outputs {
+		v, err := mergeSources(o.OutputSource, o.LinkMerge, o.PickValue, state)
+		if err != nil {
+			return nil, errf("workflow output %q: %s", o.ID, err)
+		}
+		out[o.ID] = v
+	}
+	return out, nil
+}
+
+// stepReady returns true if every source of the step's inputs has a value,
+// ignoring the inputs named in "skip".
+func (r *WorkflowRunner) stepReady(step cwl.Step, state map[string]cwl.Value, skip ...string) bool {
+Loop:
+	for _, in := range step.In {
+		for _, s := range skip {
+			if s == in.ID {
+				continue Loop
+			}
+		}
+		for _, src := range in.Source {
+			if _, ok := state[sourceKey(src)]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *WorkflowRunner) runStep(wf *cwl.Workflow, step cwl.Step, state map[string]cwl.Value) error {
+	if len(step.Scatter) > 0 {
+		return errf("step %q: scatter is not supported (yet)", step.ID)
+	}
+
+	vals, err := stepInputs(wf, step, state)
+	if err != nil {
+		return errf("step %q: %s", step.ID, err)
+	}
+
+	run, err := stepCondition(wf, step, vals)
+	if err != nil {
+		return err
+	}
+	if !run {
+		// cwl spec:
+		// "If the when expression evaluates to false, the step is skipped
+		// and all of its outputs are set to null."
+		for _, o := range step.Out {
+			state[step.ID+"/"+o.ID] = nil
+		}
+		return nil
+	}
+
+	var out cwl.Values
+	switch z := step.Run.(type) {
+	case *cwl.Workflow:
+		out, err = r.Run(z, vals)
+	default:
+		out, err = r.Executor.Execute(step.Run, vals)
+	}
+	if err != nil {
+		return errf("step %q: %w", step.ID, err)
+	}
+
+	for _, o := range step.Out {
+		state[step.ID+"/"+o.ID] = out[o.ID]
+	}
+	return nil
+}
+
+func (r *WorkflowRunner) runStream(wf *cwl.Workflow, link StreamLink, from, to cwl.Step, state map[string]cwl.Value) error {
+	fromVals, err := stepInputs(wf, from, state)
+	if err != nil {
+		return errf("step %q: %s", from.ID, err)
+	}
+	toVals, err := stepInputs(wf, to, state, link.Input)
+	if err != nil {
+		return errf("step %q: %s", to.ID, err)
+	}
+
+	se := r.Executor.(StreamExecutor)
+	fromOut, toOut, err := se.ExecuteStream(link, from.Run, fromVals, to.Run, toVals)
+	if err != nil {
+		return errf("streaming step %q to %q: %w", from.ID, to.ID, err)
+	}
+
+	for _, o := range from.Out {
+		state[from.ID+"/"+o.ID] = fromOut[o.ID]
+	}
+	for _, o := range to.Out {
+		state[to.ID+"/"+o.ID] = toOut[o.ID]
+	}
+	return nil
+}
+
+// stepInputs collects the input values of a step from the workflow state,
+// applying defaults and "valueFrom" expressions. Inputs named in "skip"
+// are left out.
+func stepInputs(wf *cwl.Workflow, step cwl.Step, state map[string]cwl.Value, skip ...string) (cwl.Values, error) {
+	vals := cwl.Values{}
+
+Loop:
+	for _, in := range step.In {
+		for _, s := range skip {
+			if s == in.ID {
+				continue Loop
+			}
+		}
+
+		v, err := mergeSources(in.Source, in.LinkMerge, in.PickValue, state)
+		if err != nil {
+			return nil, errf("input %q: %s", in.ID, err)
+		}
+		if v == nil {
+			v = in.Default
+		}
+		vals[in.ID] = v
+	}
+
+	// cwl spec:
+	// "The value of inputs in the parameter reference or expression must be
+	// the input object to the workflow step after assigning the source values,
+	// applying default, and then scattering."
+	var evaled cwl.Values
+	for _, in := range step.In {
+		if in.ValueFrom == "" {
+			continue
+		}
+		if evaled == nil {
+			evaled = cwl.Values{}
+		}
+		v, err := evalStepExpr(wf, in.ValueFrom, vals, vals[in.ID])
+		if err != nil {
+			return nil, errf("input %q: evaluating valueFrom: %s", in.ID, err)
+		}
+		evaled[in.ID] = v
+	}
+	for k, v := range evaled {
+		vals[k] = v
+	}
+	return vals, nil
+}
+
+// stepCondition evaluates the "when" expression of a step against
+// the step input values, returning false if the step should be skipped.
+func stepCondition(wf *cwl.Workflow, step cwl.Step, vals cwl.Values) (bool, error) {
+	if step.When == "" {
+		return true, nil
+	}
+	if !expr.IsExpression(step.When) {
+		return false, errf("step %q: when must be an expression, got %q", step.ID, step.When)
+	}
+	v, err := evalStepExpr(wf, step.When, vals, nil)
+	if err != nil {
+		return false, errf("step %q: evaluating when: %s", step.ID, err)
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errf("step %q: when must evaluate to a boolean, got %#v", step.ID, v)
+	}
+	return b, nil
+}
+
+// mergeSources looks up the values of a list of sources,
+// then applies the "pickValue" method, if any.
+//
+// cwl spec:
+// "The default merge method is merge_nested ... If only one source is given
+// the value is not wrapped in a list."
+func mergeSources(sources []string, method cwl.LinkMergeMethod, pick cwl.PickValueMethod, state map[string]cwl.Value) (cwl.Value, error) {
+	v, err := linkSources(sources, method, state)
+	if err != nil || pick == "" {
+		return v, err
+	}
+
+	// A single source isn't wrapped in a list, so its value is picked from.
+	vals, ok := listValues(v)
+	if !ok {
+		vals = []cwl.Value{v}
+	}
+	return pickValue(vals, pick)
+}
+
+// listValues returns the items of "v" if it's a list of any type,
+// e.g. []cwl.Value from a merge or []interface{} from an input document.
+func listValues(v cwl.Value) ([]cwl.Value, bool) {
+	if arr, ok := v.([]cwl.Value); ok {
+		return arr, true
+	}
+	if v == nil || reflect.TypeOf(v).Kind() != reflect.Slice {
+		return nil, false
+	}
+	arr := reflect.ValueOf(v)
+	out := make([]cwl.Value, arr.Len())
+	for i := range out {
+		out[i] = arr.Index(i).Interface()
+	}
+	return out, true
+}
+
+// linkSources looks up the values of a list of sources
+// and merges them with the "linkMerge" method.
+func linkSources(sources []string, method cwl.LinkMergeMethod, state map[string]cwl.Value) (cwl.Value, error) {
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	var vals []cwl.Value
+	for _, src := range sources {
+		v, ok := state[sourceKey(src)]
+		if !ok {
+			return nil, errf("unknown source %q", src)
+		}
+		vals = append(vals, v)
+	}
+
+	if len(vals) == 1 && method == "" {
+		return vals[0], nil
+	}
+
+	switch method {
+	case "", cwl.MergeNested:
+		return vals, nil
+	case cwl.MergeFlattened:
+		var out []cwl.Value
+		for _, v := range vals {
+			if arr, ok := listValues(v); ok {
+				out = append(out, arr...)
+			} else {
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+	return nil, errf("unknown linkMerge method %q", method)
+}
+
+// pickValue picks from a list of source values, which may be null,
+// for instance when they are the outputs of a skipped step.
+func pickValue(vals []cwl.Value, method cwl.PickValueMethod) (cwl.Value, error) {
+	var nonNull []cwl.Value
+	for _, v := range vals {
+		if v != nil {
+			nonNull = append(nonNull, v)
+		}
+	}
+
+	switch method {
+	case cwl.FirstNonNull:
+		if len(nonNull) == 0 {
+			return nil, errf("pickValue %s: all source values are null", method)
+		}
+		return nonNull[0], nil
+	case cwl.TheOnlyNonNull:
+		if len(nonNull) != 1 {
+			return nil, errf("pickValue %s: expected exactly one non-null source value, got %d",
+				method, len(nonNull))
+		}
+		return nonNull[0], nil
+	case cwl.AllNonNull:
+		// The result is always a list, even if it's empty.
+		if nonNull == nil {
+			nonNull = []cwl.Value{}
+		}
+		return nonNull, nil
+	}
+	return nil, errf("unknown pickValue method %q", method)
+}
+
+// sourceKey normalizes a source reference, such as "#step1/output",
+// into a key of the workflow state.
+func sourceKey(src string) string {
+	return strings.TrimPrefix(src, "#")
+}
+
+// evalStepExpr evaluates a step input expression, with "inputs"
+// set to the step input values.
+func evalStepExpr(wf *cwl.Workflow, x cwl.Expression, inputs cwl.Values, self cwl.Value) (interface{}, error) {
+	inputsData := map[string]interface{}{}
+	for k, v := range inputs {
+		d, err := toJSONMap(v)
+		if err != nil {
+			return nil, wrap(err, `marshaling "%s" for JS eval`, k)
+		}
+		if d == nil {
+			d = expr.Null
+		}
+		inputsData[k] = d
+	}
+
+	selfData, err := toJSONMap(self)
+	if err != nil {
+		return nil, wrap(err, `marshaling "self" for JS eval`)
+	}
+
+	var libs []string
+	reqs := append([]cwl.Requirement{}, wf.Requirements...)
+	reqs = append(reqs, wf.Hints...)
+	for _, req := range reqs {
+		if z, ok := req.(cwl.InlineJavascriptRequirement); ok {
+			libs = z.ExpressionLib
+		}
+	}
+
+	return expr.Eval(x, libs, map[string]interface{}{
+		"inputs": inputsData,
+		"self":   selfData,
+	})
+}
+
+/*** Streaming between workflow steps ***/
+
+// StreamLink describes a streamable output of a step which feeds
+// a streamable input of the step immediately following it.
+type StreamLink struct {
+	From, Output string
+	To, Input    string
+}
+
+// StreamLinks finds adjacent pairs of steps which agree to stream a file
+// from one to the other: the first step's output is a streamable File, the
+// second step's input is a streamable File, and the output is used by
+// nothing else in the workflow (so it never needs to be written to disk).
+func StreamLinks(wf *cwl.Workflow) []StreamLink {
+	// Count the consumers of every source.
+	uses := map[string]int{}
+	for _, step := range wf.Steps {
+		for _, in := range step.In {
+			for _, src := range in.Source {
+				uses[sourceKey(src)]++
+			}
+		}
+	}
+	for _, out := range wf.Outputs {
+		for _, src := range out.OutputSource {
+			uses[sourceKey(src)]++
+		}
+	}
+
+	var links []StreamLink
+	for i := 0; i+1 < len(wf.Steps); i++ {
+		from := wf.Steps[i]
+		to := wf.Steps[i+1]
+
+		// A conditional step might be skipped, leaving nothing to stream.
+		if from.When != "" || to.When != "" {
+			continue
+		}
+
+		fromTool, ok := from.Run.(*cwl.Tool)
+		if !ok {
+			continue
+		}
+		toTool, ok := to.Run.(*cwl.Tool)
+		if !ok {
+			continue
+		}
+
+		for _, in := range to.In {
+			if len(in.Source) != 1 || in.ValueFrom != "" {
+				continue
+			}
+			src := sourceKey(in.Source[0])
+			if !strings.HasPrefix(src, from.ID+"/") || uses[src] != 1 {
+				continue
+			}
+			outID := strings.TrimPrefix(src, from.ID+"/")
+
+			if !streamableOutput(fromTool, outID) || !streamableInput(toTool, in.ID) {
+				continue
+			}
+			links = append(links, StreamLink{
+				From: from.ID, Output: outID,
+				To: to.ID, Input: in.ID,
+			})
+			// Only one stream per pair of steps.
+			break
+		}
+	}
+	return links
+}
+
+func findStreamLink(links []StreamLink, from string) (StreamLink, bool) {
+	for _, l := range links {
+		if l.From == from {
+			return l, true
+		}
+	}
+	return StreamLink{}, false
+}
+
+func streamableOutput(tool *cwl.Tool, id string) bool {
+	for _, out := range tool.Outputs {
+		if out.ID != id || len(out.Type) != 1 {
+			continue
+		}
+		switch out.Type[0].(type) {
+		case cwl.Stdout:
+			return true
+		case cwl.FileType:
+			// The output must be written to a known file name,
+			// so that a named pipe can be put in its place.
+			b := out.OutputBinding
+			if !out.Streamable || b == nil || len(b.Glob) != 1 {
+				return false
+			}
+			glob := b.Glob[0]
+			return !expr.IsExpression(glob) && !strings.ContainsAny(string(glob), "*?[")
+		}
+	}
+	return false
+}
+
+func streamableInput(tool *cwl.Tool, id string) bool {
+	for _, in := range tool.Inputs {
+		if in.ID != id || !in.Streamable || len(in.Type) != 1 {
+			continue
+		}
+		_, ok := in.Type[0].(cwl.FileType)
+		return ok
+	}
+	return false
+}
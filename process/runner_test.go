@@ -0,0 +1,212 @@
+package process
+
+import (
+	"cwl"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeExecutor "runs" a tool by returning a value for each
+// output, built from the tool ID and the input values.
+type fakeExecutor struct {
+	ran []string
+}
+
+func (f *fakeExecutor) Execute(doc cwl.Document, vals cwl.Values) (cwl.Values, error) {
+	tool := doc.(*cwl.Tool)
+	f.ran = append(f.ran, tool.ID)
+	out := cwl.Values{}
+	for _, o := range tool.Outputs {
+		out[o.ID] = []cwl.Value{tool.ID, vals["in"]}
+	}
+	return out, nil
+}
+
+func streamWorkflow() *cwl.Workflow {
+	producer := &cwl.Tool{
+		ID:      "producer",
+		Outputs: []cwl.CommandOutput{{ID: "out", Type: []cwl.OutputType{cwl.Stdout{}}}},
+	}
+	consumer := &cwl.Tool{
+		ID: "consumer",
+		Inputs: []cwl.CommandInput{
+			{ID: "in", Streamable: true, Type: []cwl.InputType{cwl.FileType{}}},
+		},
+		Outputs: []cwl.CommandOutput{{ID: "count", Type: []cwl.OutputType{cwl.Stdout{}}}},
+	}
+	return &cwl.Workflow{
+		Inputs:  []cwl.WorkflowInput{{ID: "inp", Type: []cwl.InputType{cwl.String{}}}},
+		Outputs: []cwl.WorkflowOutput{{ID: "result", OutputSource: []string{"second/count"}}},
+		Steps: []cwl.Step{
+			// Declared out of order, to check dependency ordering.
+			{
+				ID:  "second",
+				In:  []cwl.StepInput{{ID: "in", Source: []string{"#first/out"}}},
+				Out: []cwl.StepOutput{{ID: "count"}},
+				Run: consumer,
+			},
+			{
+				ID:  "first",
+				In:  []cwl.StepInput{{ID: "in", Source: []string{"inp"}}},
+				Out: []cwl.StepOutput{{ID: "out"}},
+				Run: producer,
+			},
+		},
+	}
+}
+
+func TestWorkflowRunner(t *testing.T) {
+	wf := streamWorkflow()
+	ex := &fakeExecutor{}
+	r := WorkflowRunner{Executor: ex}
+
+	out, err := r.Run(wf, cwl.Values{"inp": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ex.ran, []string{"producer", "consumer"}) {
+		t.Errorf("unexpected run order: %v", ex.ran)
+	}
+
+	expect := cwl.Values{
+		"result": []cwl.Value{"consumer", []cwl.Value{"producer", "hello"}},
+	}
+	if !reflect.DeepEqual(out, expect) {
+		t.Errorf("expected %#v, got %#v", expect, out)
+	}
+}
+
+func TestStreamLinks(t *testing.T) {
+	wf := streamWorkflow()
+	if links := StreamLinks(wf); links != nil {
+		t.Errorf("steps are not adjacent in dependency order, expected no links, got %v", links)
+	}
+
+	wf.Steps[0], wf.Steps[1] = wf.Steps[1], wf.Steps[0]
+	links := StreamLinks(wf)
+	expect := []StreamLink{{From: "first", Output: "out", To: "second", Input: "in"}}
+	if !reflect.DeepEqual(links, expect) {
+		t.Errorf("expected %v, got %v", expect, links)
+	}
+
+	// An output used by anything else must be written to disk.
+	wf.Outputs = append(wf.Outputs, cwl.WorkflowOutput{
+		ID: "raw", OutputSource: []string{"first/out"},
+	})
+	if links := StreamLinks(wf); links != nil {
+		t.Errorf("expected no links, got %v", links)
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	state := map[string]cwl.Value{
+		"a": []cwl.Value{1, 2},
+		"b": 3,
+	}
+
+	v, err := mergeSources([]string{"a", "b"}, cwl.MergeFlattened, "", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []cwl.Value{1, 2, 3}) {
+		t.Errorf("unexpected merge_flattened result: %v", v)
+	}
+
+	v, err = mergeSources([]string{"#b"}, "", "", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 3 {
+		t.Errorf("unexpected single source result: %v", v)
+	}
+
+	if _, err := mergeSources([]string{"missing"}, "", "", state); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestConditionalSteps(t *testing.T) {
+	tool := func(id string) *cwl.Tool {
+		return &cwl.Tool{ID: id, Outputs: []cwl.CommandOutput{{ID: "out"}}}
+	}
+	wf := &cwl.Workflow{
+		Inputs: []cwl.WorkflowInput{{ID: "big"}},
+		Outputs: []cwl.WorkflowOutput{
+			{ID: "first", OutputSource: []string{"small/out", "large/out"}, PickValue: cwl.FirstNonNull},
+			{ID: "all", OutputSource: []string{"small/out", "large/out"}, PickValue: cwl.AllNonNull},
+		},
+		Steps: []cwl.Step{
+			{
+				ID:   "small",
+				In:   []cwl.StepInput{{ID: "in", Source: []string{"big"}}},
+				Out:  []cwl.StepOutput{{ID: "out"}},
+				When: "$(!inputs.in)",
+				Run:  tool("small"),
+			},
+			{
+				ID:   "large",
+				In:   []cwl.StepInput{{ID: "in", Source: []string{"big"}}},
+				Out:  []cwl.StepOutput{{ID: "out"}},
+				When: "$(inputs.in)",
+				Run:  tool("large"),
+			},
+		},
+	}
+
+	ex := &fakeExecutor{}
+	r := WorkflowRunner{Executor: ex}
+	out, err := r.Run(wf, cwl.Values{"big": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ex.ran, []string{"large"}) {
+		t.Errorf("expected only the large step to run, ran %v", ex.ran)
+	}
+	large := []cwl.Value{"large", true}
+	expect := cwl.Values{"first": large, "all": []cwl.Value{large}}
+	if !reflect.DeepEqual(out, expect) {
+		t.Errorf("expected %#v, got %#v", expect, out)
+	}
+
+	// Both steps are skipped, so there's no value to pick.
+	wf.Steps[1].When = "$(false)"
+	_, err = r.Run(wf, cwl.Values{"big": true})
+	if err == nil || !strings.Contains(err.Error(), `workflow output "first"`) {
+		t.Errorf("expected pickValue error, got %v", err)
+	}
+
+	wf.Steps[1].When = "$(inputs.in)"
+	_, err = r.Run(wf, cwl.Values{"big": "yes"})
+	if err == nil || !strings.Contains(err.Error(), `step "large": when must evaluate to a boolean`) {
+		t.Errorf("expected when error naming the step, got %v", err)
+	}
+}
+
+func TestPickValue(t *testing.T) {
+	state := map[string]cwl.Value{"a": nil, "b": 1, "c": 2}
+
+	v, err := mergeSources([]string{"a", "b"}, "", cwl.TheOnlyNonNull, state)
+	if err != nil || v != 1 {
+		t.Errorf("unexpected the_only_non_null result: %v %v", v, err)
+	}
+	if _, err := mergeSources([]string{"b", "c"}, "", cwl.TheOnlyNonNull, state); err == nil {
+		t.Error("expected error for more than one non-null value")
+	}
+	v, err = mergeSources([]string{"a"}, "", cwl.AllNonNull, state)
+	if err != nil || !reflect.DeepEqual(v, []cwl.Value{}) {
+		t.Errorf("unexpected all_non_null result: %#v %v", v, err)
+	}
+
+	// A single list source is picked from, whatever the type of the list.
+	state["list"] = []interface{}{nil, 3}
+	v, err = mergeSources([]string{"list"}, "", cwl.FirstNonNull, state)
+	if err != nil || v != 3 {
+		t.Errorf("unexpected first_non_null result for a list: %#v %v", v, err)
+	}
+	v, err = mergeSources([]string{"list"}, "", cwl.AllNonNull, state)
+	if err != nil || !reflect.DeepEqual(v, []cwl.Value{3}) {
+		t.Errorf("unexpected all_non_null result for a list: %#v %v", v, err)
+	}
+}
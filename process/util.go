@@ -5,6 +5,7 @@ import (
 	"cwl"
 	"github.com/kr/pretty"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -18,12 +19,18 @@ func wrap(err error, msg string, args ...interface{}) error {
 }
 
 // getPos is a helper for accessing the Position field
-// of a possibly nil CommandLineBinding
-func getPos(in *cwl.CommandLineBinding) int {
-	if in == nil {
+// of a possibly nil CommandLineBinding. Positions which aren't
+// an int are returned as an expression, to be evaluated once
+// the inputs are bound, see evalSortKey.
+func getPos(in *cwl.CommandLineBinding) interface{} {
+	if in == nil || in.Position == "" {
 		return 0
 	}
-	return in.Position
+	i, err := strconv.Atoi(strings.TrimSpace(string(in.Position)))
+	if err != nil {
+		return in.Position
+	}
+	return i
 }
 
 func debug(args ...interface{}) {
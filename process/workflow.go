@@ -224,7 +224,7 @@ func (process *WFProcess) bindInput(
 	name string,
 	types []cwl.InputType,
 	clb *cwl.CommandLineBinding,
-	secondaryFiles []cwl.Expression,
+	secondaryFiles []cwl.SecondaryFile,
 	val interface{},
 	key sortKey,
 ) ([]*Binding, error) {
@@ -386,8 +386,10 @@ Loop:
 			}
 			// TODO figure out a good way to do this.
 			//f.Path = "/inputs/" + f.Path			
-			for _, expr := range secondaryFiles {
-				process.resolveSecondaryFiles(f, expr)
+			for _, sf := range secondaryFiles {
+				if err := process.resolveSecondaryFiles(&f, sf, false); err != nil {
+					return nil, errf("resolving secondary files: %s", err)
+				}
 			}
 
 			return []*Binding{
@@ -498,7 +500,8 @@ func (process *WFProcess) resolveFile(f cwl.File, loadContents bool) (cwl.File,
 	return f, nil
 }
 
-func (process *WFProcess) resolveSecondaryFiles(file cwl.File, x cwl.Expression) error {
+func (process *WFProcess) resolveSecondaryFiles(file *cwl.File, sf cwl.SecondaryFile, output bool) error {
+	x := sf.Pattern
 
 	// cwl spec:
 	// "If the value is an expression, the value of self in the expression
@@ -537,6 +540,16 @@ func (process *WFProcess) resolveSecondaryFiles(file cwl.File, x cwl.Expression)
 		Location: location + pattern,
 	}
 
+	required, err := secondaryFileRequired(sf, output, process.eval, *file)
+	if err != nil {
+		return err
+	}
+	if !required {
+		if _, err := process.fs.Info(sec.Location); err == ErrFileNotFound {
+			return nil
+		}
+	}
+
 	// TODO does LoadContents apply to secondary files? not in the spec
 	f, err := process.resolveFile(sec, false)
 	if err != nil {
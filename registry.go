@@ -0,0 +1,217 @@
+package cwl
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/commondream/yamlast"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// CustomRequirement is embedded in a struct to make it a Requirement,
+// so that it can be registered with RegisterRequirement:
+//
+//	type Queue struct {
+//	  cwl.CustomRequirement
+//	  Name string `json:"name"`
+//	}
+type CustomRequirement struct{}
+
+func (CustomRequirement) requirement() {}
+
+// RequirementType describes a requirement or hint class
+// which isn't built in, such as an in-house extension.
+type RequirementType struct {
+	// Class is the value of the "class" field, e.g. "acme:Queue".
+	// Classes are matched case insensitively.
+	Class string
+
+	// Type is a value of the Go type the requirement is loaded into,
+	// e.g. Queue{}. Its fields are loaded like those of the built-in
+	// requirements, using their "json" tags.
+	Type Requirement
+
+	// Load, if set, loads the requirement instead, given its fields.
+	Load func(fields Values) (Requirement, error)
+
+	// Marshal, if set, marshals the requirement to a JSON object
+	// instead of json.Marshal. The "class" field is added to the object,
+	// so Marshal shouldn't include it.
+	Marshal func(Requirement) ([]byte, error)
+}
+
+var registry = struct {
+	sync.RWMutex
+	byClass map[string]RequirementType
+	byType  map[reflect.Type]RequirementType
+}{
+	byClass: map[string]RequirementType{},
+	byType:  map[reflect.Type]RequirementType{},
+}
+
+// RegisterRequirement registers a requirement or hint class, so that
+// it is loaded into rt.Type, instead of an UnknownRequirement.
+//
+// RegisterRequirement panics if the class or type is already registered,
+// or if the class is a built-in requirement, like database/sql.Register.
+func RegisterRequirement(rt RequirementType) {
+	if rt.Class == "" {
+		panic("cwl: RegisterRequirement class is empty")
+	}
+	if rt.Type == nil {
+		panic("cwl: RegisterRequirement type is nil for " + rt.Class)
+	}
+	if isBuiltinRequirement(rt.Class) {
+		panic("cwl: RegisterRequirement called for built-in class " + rt.Class)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	class := strings.ToLower(rt.Class)
+	typ := reflect.TypeOf(rt.Type)
+	if _, dup := registry.byClass[class]; dup {
+		panic("cwl: RegisterRequirement called twice for class " + rt.Class)
+	}
+	if _, dup := registry.byType[typ]; dup {
+		panic("cwl: RegisterRequirement called twice for type " + typ.String())
+	}
+	registry.byClass[class] = rt
+	registry.byType[typ] = rt
+}
+
+// LookupRequirement returns the registered requirement type of a class.
+func LookupRequirement(class string) (RequirementType, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	rt, ok := registry.byClass[strings.ToLower(class)]
+	return rt, ok
+}
+
+// RequirementClass returns the value of the "class" field of a requirement.
+func RequirementClass(r Requirement) string {
+	if u, ok := r.(UnknownRequirement); ok {
+		return u.Name
+	}
+
+	typ := reflect.TypeOf(r)
+	registry.RLock()
+	rt, ok := registry.byType[typ]
+	registry.RUnlock()
+	if ok {
+		return rt.Class
+	}
+
+	// Built-in requirements are named after their class.
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
+
+// MarshalRequirement marshals a requirement to JSON, including its "class" field.
+func MarshalRequirement(r Requirement) ([]byte, error) {
+	registry.RLock()
+	rt, ok := registry.byType[reflect.TypeOf(r)]
+	registry.RUnlock()
+	if !ok {
+		// Built-in requirements add their own class field, see json.go.
+		return json.Marshal(r)
+	}
+
+	var b []byte
+	var err error
+	if rt.Marshal != nil {
+		b, err = rt.Marshal(r)
+	} else {
+		b, err = json.Marshal(r)
+	}
+	if err != nil {
+		return nil, errf("marshaling %s: %s", rt.Class, err)
+	}
+
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || b[0] != '{' {
+		return nil, errf("marshaling %s: expected a JSON object", rt.Class)
+	}
+	class, _ := json.Marshal(rt.Class)
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"class":`)
+	buf.Write(class)
+	rest := bytes.TrimSpace(b[1:])
+	if rest[0] != '}' {
+		buf.WriteByte(',')
+	}
+	buf.Write(rest)
+	return buf.Bytes(), nil
+}
+
+// loadRegisteredReq loads a requirement of a registered class.
+func (l *loader) loadRegisteredReq(rt RequirementType, n node) (Requirement, error) {
+	if rt.Load != nil {
+		fields := Values{}
+		if err := l.load(n, &fields); err != nil {
+			return nil, err
+		}
+		delete(fields, "class")
+
+		r, err := rt.Load(fields)
+		if err != nil {
+			return nil, l.errorAt(n, "loading %s: %s", rt.Class, err)
+		}
+		return r, nil
+	}
+
+	typ := reflect.TypeOf(rt.Type)
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	v := reflect.New(typ)
+	if err := l.load(n, v.Interface()); err != nil {
+		return nil, err
+	}
+	if isPtr {
+		return v.Interface().(Requirement), nil
+	}
+	return v.Elem().Interface().(Requirement), nil
+}
+
+// isBuiltinRequirement returns true if the class is
+// handled by loadReqByName without the registry.
+func isBuiltinRequirement(class string) bool {
+	l := &loader{resolver: NoResolve()}
+	n := &yamlast.Node{Kind: yamlast.MappingNode}
+	r, err := l.loadReqByName(class, n)
+	if err != nil {
+		return false
+	}
+	if _, ok := r.(UnknownRequirement); ok {
+		return false
+	}
+	_, registered := LookupRequirement(class)
+	return !registered
+}
+
+// requirementList marshals requirements with MarshalRequirement,
+// so that registered requirements include their class.
+type requirementList []Requirement
+
+func (x requirementList) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, r := range x {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		b, err := MarshalRequirement(r)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
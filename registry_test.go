@@ -0,0 +1,112 @@
+package cwl
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testQueue struct {
+	CustomRequirement
+	Name     string `json:"name"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+type testCostCenter struct {
+	CustomRequirement
+	Code string
+}
+
+func init() {
+	RegisterRequirement(RequirementType{
+		Class: "acme:Queue",
+		Type:  testQueue{},
+	})
+	RegisterRequirement(RequirementType{
+		Class: "acme:CostCenter",
+		Type:  &testCostCenter{},
+		Load: func(fields Values) (Requirement, error) {
+			code, ok := fields["code"].(string)
+			if !ok {
+				return nil, fmt.Errorf("code must be a string")
+			}
+			return &testCostCenter{Code: code}, nil
+		},
+		Marshal: func(r Requirement) ([]byte, error) {
+			return json.Marshal(map[string]string{"code": r.(*testCostCenter).Code})
+		},
+	})
+}
+
+func TestRegisterRequirement(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: CommandLineTool
+inputs: []
+outputs: []
+requirements:
+  - class: acme:Queue
+    name: long
+    priority: 2
+hints:
+  - class: acme:CostCenter
+    code: "1234"
+`
+	d, warnings, err := LoadDocumentBytesWithOptions([]byte(doc), ".", LoadOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	tool := d.(*Tool)
+
+	q, ok := tool.Requires("acme:queue")
+	if !ok || !reflect.DeepEqual(q, testQueue{Name: "long", Priority: 2}) {
+		t.Errorf("unexpected queue: %#v", q)
+	}
+	cc, ok := tool.Requires("acme:CostCenter")
+	if !ok || cc.(*testCostCenter).Code != "1234" {
+		t.Errorf("unexpected cost center: %#v", cc)
+	}
+
+	b, err := json.Marshal(tool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := LoadDocumentBytes(b, ".", nil)
+	if err != nil {
+		t.Fatalf("reloading %s: %s", b, err)
+	}
+	tool2 := d2.(*Tool)
+	if !reflect.DeepEqual(tool.Requirements, tool2.Requirements) ||
+		!reflect.DeepEqual(tool.Hints, tool2.Hints) {
+		t.Errorf("requirements didn't round trip: %s", b)
+	}
+
+	_, _, err = LoadDocumentBytesWithOptions([]byte(`
+cwlVersion: v1.0
+class: CommandLineTool
+inputs: []
+outputs: []
+hints:
+  - class: acme:CostCenter
+    code: [1]
+`), ".", LoadOptions{})
+	if e, ok := err.(*LoadError); !ok || e.Line != 7 {
+		t.Errorf("expected positioned load error, got %v", err)
+	}
+}
+
+func TestRegisterBuiltinRequirement(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a built-in class to panic")
+		}
+	}()
+	RegisterRequirement(RequirementType{
+		Class: "DockerRequirement",
+		Type:  testQueue{},
+	})
+}
@@ -2,6 +2,8 @@ package cwl
 
 type UnknownRequirement struct {
 	Name string
+
+	Extensions Extensions `json:"-"`
 }
 
 type DockerRequirement struct {
@@ -11,6 +13,8 @@ type DockerRequirement struct {
 	Import          string `json:"dockerImport,omitempty"`
 	ImageID         string `json:"dockerImageID,omitempty"`
 	OutputDirectory string `json:"dockerOutputDirectory,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type ResourceRequirement struct {
@@ -22,22 +26,31 @@ type ResourceRequirement struct {
 	TmpDirMax Expression `json:"tmpdirMax,omitempty"`
 	OutDirMin Expression `json:"outdirMin,omitempty"`
 	OutDirMax Expression `json:"outdirMax,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type EnvVarRequirement struct {
 	EnvDef map[string]Expression `json:"envDef,omitempty"`
 	EnvExpr []Expression `json:"envExpr,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type ShellCommandRequirement struct {
+	Extensions Extensions `json:"-"`
 }
 
 type InlineJavascriptRequirement struct {
 	ExpressionLib []string `json:"expressionLib,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type SchemaDefRequirement struct {
 	Types []SchemaDef `json:"types,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type SchemaDef struct {
@@ -47,6 +60,8 @@ type SchemaDef struct {
 
 type SoftwareRequirement struct {
 	Packages []SoftwarePackage `json:"packages,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type SoftwarePackage struct {
@@ -60,6 +75,8 @@ type InitialWorkDirListing struct{}
 type InitialWorkDirRequirement struct {
 	// TODO the most difficult union type
 	Listing InitialWorkDirListing `json:"listing,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type Dirent struct {
@@ -69,15 +86,59 @@ type Dirent struct {
 }
 
 type SubworkflowFeatureRequirement struct {
+	Extensions Extensions `json:"-"`
 }
 
 type ScatterFeatureRequirement struct {
+	Extensions Extensions `json:"-"`
 }
 
 type MultipleInputFeatureRequirement struct {
+	Extensions Extensions `json:"-"`
 }
 
 type StepInputExpressionRequirement struct {
+	Extensions Extensions `json:"-"`
+}
+
+// ToolTimeLimit sets the maximum number of seconds a tool may run.
+// Timelimit is an int or an expression; zero means no limit.
+type ToolTimeLimit struct {
+	Timelimit Expression `json:"timelimit"`
+
+	Extensions Extensions `json:"-"`
+}
+
+// LoadListingRequirement sets the default loadListing of directory inputs.
+// Added in v1.1.
+type LoadListingRequirement struct {
+	LoadListing LoadListingMethod `json:"loadListing,omitempty"`
+
+	Extensions Extensions `json:"-"`
+}
+
+// NetworkAccess allows a tool to access the network.
+// NetworkAccess is a boolean or an expression. Added in v1.1.
+type NetworkAccess struct {
+	NetworkAccess Expression `json:"networkAccess"`
+
+	Extensions Extensions `json:"-"`
+}
+
+// WorkReuse allows or disallows reusing the results of earlier runs.
+// EnableReuse is a boolean or an expression. Added in v1.1.
+type WorkReuse struct {
+	EnableReuse Expression `json:"enableReuse"`
+
+	Extensions Extensions `json:"-"`
+}
+
+// InplaceUpdateRequirement allows a tool to modify writable
+// input files in place. Added in v1.1.
+type InplaceUpdateRequirement struct {
+	InplaceUpdate bool `json:"inplaceUpdate"`
+
+	Extensions Extensions `json:"-"`
 }
 /*
 type PreCMDRequirement struct {
@@ -91,4 +152,6 @@ type PostCMDRequirement struct {
 type LRMRequirement struct {
 	Type 	string 		`json:"type,omitempty"`
 	LRMDef map[string]Expression `json:"lrmDef,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
\ No newline at end of file
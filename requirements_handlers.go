@@ -16,7 +16,7 @@ func (l *loader) SeqToRequirementSlice(n node) ([]Requirement, error) {
 			}
 			reqs = append(reqs, r.(Requirement))
 		default:
-			panic("unknown node kind")
+			return nil, l.errorAt(c, "requirement must be a mapping")
 		}
 	}
 	return reqs, nil
@@ -34,7 +34,11 @@ requirements:
 */
 func (l *loader) MappingToRequirementSlice(n node) ([]Requirement, error) {
 	var reqs []Requirement
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		x, err := l.loadReqByName(k, v)
@@ -57,6 +61,10 @@ func (l *loader) SeqToInitialWorkDirListing(n node) (InitialWorkDirListing, erro
 }
 
 func (l *loader) loadReqByName(name string, n node) (Requirement, error) {
+	if err := l.checkClassVersion(n, name); err != nil {
+		return nil, err
+	}
+
 	switch strings.ToLower(name) {
 	case "dockerrequirement":
 		d := DockerRequirement{}
@@ -91,13 +99,41 @@ func (l *loader) loadReqByName(name string, n node) (Requirement, error) {
 		err := l.load(n, &r)
 		return r, err
 	case "subworkflowfeaturerequirement":
-		return SubworkflowFeatureRequirement{}, nil
+		r := SubworkflowFeatureRequirement{}
+		err := l.load(n, &r)
+		return r, err
 	case "scatterfeaturerequirement":
-		return ScatterFeatureRequirement{}, nil
+		r := ScatterFeatureRequirement{}
+		err := l.load(n, &r)
+		return r, err
 	case "multipleinputfeaturerequirement":
-		return MultipleInputFeatureRequirement{}, nil
+		r := MultipleInputFeatureRequirement{}
+		err := l.load(n, &r)
+		return r, err
 	case "stepinputexpressionrequirement":
-		return StepInputExpressionRequirement{}, nil
+		r := StepInputExpressionRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "tooltimelimit":
+		r := ToolTimeLimit{}
+		err := l.load(n, &r)
+		return r, err
+	case "loadlistingrequirement":
+		r := LoadListingRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "networkaccess":
+		r := NetworkAccess{}
+		err := l.load(n, &r)
+		return r, err
+	case "workreuse":
+		r := WorkReuse{}
+		err := l.load(n, &r)
+		return r, err
+	case "inplaceupdaterequirement":
+		r := InplaceUpdateRequirement{}
+		err := l.load(n, &r)
+		return r, err
 	/*
 	case "precmdrequirement":
 		r := PreCMDRequirement{}
@@ -113,7 +149,40 @@ func (l *loader) loadReqByName(name string, n node) (Requirement, error) {
 		err := l.load(n, &r)
 		return r, err
 	}
-	return UnknownRequirement{Name: name}, nil
-	// TODO logging
-	//return nil, fmt.Errorf("unknown requirement name: %s", name)
+	if rt, ok := LookupRequirement(name); ok {
+		return l.loadRegisteredReq(rt, n)
+	}
+	if !l.isNamespaced(name) {
+		if err := l.warn(n, "unknown requirement class %q", name); err != nil {
+			return nil, err
+		}
+	}
+	return l.loadUnknownReq(name, n)
+}
+
+// loadUnknownReq keeps the fields of a requirement of an unknown class,
+// so that it can be marshaled back out.
+func (l *loader) loadUnknownReq(name string, n node) (Requirement, error) {
+	u := UnknownRequirement{Name: name}
+	if n.Kind != yamlast.MappingNode {
+		return u, nil
+	}
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
+		if kv.k == "class" {
+			continue
+		}
+		x, err := l.rawValue(kv.v)
+		if err != nil {
+			return nil, err
+		}
+		if u.Extensions == nil {
+			u.Extensions = Extensions{}
+		}
+		u.Extensions[kv.k] = x
+	}
+	return u, nil
 }
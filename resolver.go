@@ -2,7 +2,6 @@ package cwl
 
 import (
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"path/filepath"
 )
@@ -21,7 +20,8 @@ type Resolver interface {
 }
 
 // DefaultResolver is a document location resolver which
-// resolves local file paths.
+// resolves local file paths, HTTP(S) URLs and files in
+// a git repository, such as "tools.git@v1.4:align/bwa.cwl".
 type DefaultResolver struct{}
 
 func (DefaultResolver) Resolve(base, loc string) ([]byte, string, error) {
@@ -32,6 +32,9 @@ func (DefaultResolver) Resolve(base, loc string) ([]byte, string, error) {
 		}
 		return b, u.String(), nil
 	}
+	if gl, ok := isGit(base, loc); ok {
+		return gl.resolve()
+	}
 
 	if !filepath.IsAbs(loc) {
 		loc = filepath.Clean(filepath.Join(base, loc))
@@ -45,6 +48,11 @@ func (DefaultResolver) Resolve(base, loc string) ([]byte, string, error) {
 	return b, dir, nil
 }
 
+// revalidate implements revalidator, for CachingResolver.
+func (DefaultResolver) revalidate(u *url.URL, v validators) ([]byte, validators, bool, error) {
+	return (&HTTPResolver{}).revalidate(u, v)
+}
+
 // NoResolve is a special case resolver which does not
 // resolve documents, but instead creates `DocumentRef`
 // instances in the document tree.
@@ -57,6 +65,9 @@ type noResolver struct {
 }
 
 func isHTTP(base, loc string) (*url.URL, bool) {
+	if l, err := url.Parse(loc); err == nil && (l.Scheme == "http" || l.Scheme == "https") {
+		return l, true
+	}
 	if base == "" {
 		base, loc = loc, base
 	}
@@ -75,14 +86,6 @@ func isHTTP(base, loc string) (*url.URL, bool) {
 }
 
 func httpGet(u *url.URL) ([]byte, error) {
-	resp, err := http.Get(u.String())
-	if err != nil {
-		return nil, errf(`failed to resolve HTTP URL %s: %s`, u.String(), err)
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errf(`failed to read HTTP response body for %s: %s`, u.String(), err)
-	}
-	return body, nil
+	_, body, err := (&HTTPResolver{}).get(u, nil)
+	return body, err
 }
@@ -0,0 +1,191 @@
+package cwl
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRoundTrip checks that every example document loads back
+// to the same value after marshaling it to JSON and YAML, and that
+// marshaling doesn't add fields which aren't CWL fields.
+// Documents are loaded both with and without resolving references,
+// which covers packed ($graph) documents.
+func TestRoundTrip(t *testing.T) {
+	for _, path := range exampleFiles(t) {
+		for _, r := range []Resolver{DefaultResolver{}, NoResolve()} {
+			doc, warnings, err := LoadWithOptions(path, LoadOptions{Resolver: r})
+			if err != nil || doc == nil {
+				// Not a document, such as an input values file.
+				continue
+			}
+			checkRoundTrip(t, path, doc, filepath.Dir(path), r, len(warnings))
+		}
+	}
+}
+
+// TestRoundTripClasses checks the classes and fields
+// which aren't covered by the examples.
+func TestRoundTripClasses(t *testing.T) {
+	docs := map[string]string{
+		"script": `
+cwlVersion: v1.0
+class: ScriptTool
+requirements:
+  - class: LRMRequirement
+    type: slurm
+    lrmDef:
+      queue: long
+      mem: $(inputs.mem)
+  - class: acme:Unknown
+    nested: {a: [1, 2.5, true, null]}
+inputs:
+  - id: mem
+    type: int
+outputs: []
+`,
+		"graph": `
+cwlVersion: v1.0
+$graph:
+  - id: main
+    class: Workflow
+    inputs:
+      color:
+        type:
+          type: enum
+          symbols: [red, green]
+    outputs:
+      out:
+        type:
+          type: enum
+          symbols: [a, b]
+        outputSource: echo/out
+    steps:
+      echo:
+        run: "#echo"
+        in: {color: color}
+        out: [out]
+  - id: echo
+    class: ExpressionTool
+    requirements:
+      - class: SchemaDefRequirement
+        types:
+          - name: Color
+            type: enum
+            symbols: [red, green]
+    inputs: []
+    outputs: []
+    expression: "$({})"
+`,
+	}
+	for name, doc := range docs {
+		d, warnings, err := LoadDocumentBytesWithOptions([]byte(doc), ".", LoadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		checkRoundTrip(t, name, d, ".", nil, len(warnings))
+	}
+
+	checkRoundTrip(t, "expression tool", &ExpressionTool{
+		Class:      "ExpressionTool",
+		CWLVersion: "v1.0",
+		Expression: "$({})",
+	}, ".", nil, 0)
+
+	// Gid is a database key, not part of the document.
+	wf := &Workflow{Gid: 1}
+	b, _ := json.Marshal(wf)
+	if string(b) != `{"class":"Workflow"}` {
+		t.Errorf("unexpected workflow JSON: %s", b)
+	}
+}
+
+// checkRoundTrip marshals a document and loads it back. "warnings" is the
+// number of warnings loading the original document, such as unknown fields
+// it already had; the marshaled document must not have any more.
+func checkRoundTrip(t *testing.T, name string, doc Document, base string, r Resolver, warnings int) {
+	t.Helper()
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Errorf("%s: marshaling JSON: %s", name, err)
+		return
+	}
+	y, err := MarshalYAML(doc)
+	if err != nil {
+		t.Errorf("%s: marshaling YAML: %s", name, err)
+		return
+	}
+	checkCWLFields(t, name, b)
+	// Where a document was loaded from isn't marshaled.
+	clearSourceFiles(doc)
+
+	for format, b := range map[string][]byte{"JSON": b, "YAML": y} {
+		got, w, err := LoadDocumentBytesWithOptions(b, base, LoadOptions{Resolver: r})
+		if err != nil {
+			t.Errorf("%s: loading %s: %s\n%s", name, format, err, b)
+			continue
+		}
+		if len(w) > warnings {
+			t.Errorf("%s: %s has fields which aren't CWL fields: %v\n%s", name, format, w, b)
+		}
+		clearSourceFiles(got)
+		if !reflect.DeepEqual(got, doc) {
+			b2, _ := json.Marshal(got)
+			t.Errorf("%s: %s didn't round trip:\n%s\n%s", name, format, b, b2)
+		}
+	}
+}
+
+// clearSourceFiles clears the SourceFile of a document
+// and the documents it runs.
+func clearSourceFiles(doc Document) {
+	switch z := doc.(type) {
+	case *Workflow:
+		z.SourceFile = ""
+		for _, step := range z.Steps {
+			clearSourceFiles(step.Run)
+		}
+	case *Tool:
+		z.SourceFile = ""
+	case *ExpressionTool:
+		z.SourceFile = ""
+	case Graph:
+		for _, d := range z.Docs {
+			clearSourceFiles(d)
+		}
+	}
+}
+
+// internalFields are the JSON names of fields which the loader
+// would accept, but which aren't CWL fields.
+var internalFields = map[string]bool{"sourcefile": true, "gid": true}
+
+// checkCWLFields checks that marshaled JSON has no internal fields.
+func checkCWLFields(t *testing.T, name string, b []byte) {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Errorf("%s: %s", name, err)
+		return
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch z := v.(type) {
+		case map[string]interface{}:
+			for k, x := range z {
+				if internalFields[strings.ToLower(k)] {
+					t.Errorf("%s: marshaled internal field %q", name, k)
+				}
+				walk(x)
+			}
+		case []interface{}:
+			for _, x := range z {
+				walk(x)
+			}
+		}
+	}
+	walk(v)
+}
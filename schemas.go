@@ -1,6 +1,7 @@
 package cwl
 
 type InputRecord struct {
+	Name   string       `json:"name,omitempty"`
 	Label  string       `json:"label,omitempty"`
 	Fields []InputField `json:"fields,omitempty"`
 }
@@ -14,18 +15,21 @@ type InputField struct {
 }
 
 type InputEnum struct {
+	Name         string              `json:"name,omitempty"`
 	Label        string              `json:"label,omitempty"`
 	Symbols      []string            `json:"symbols,omitempty"`
 	InputBinding *CommandLineBinding `json:"inputBinding,omitempty"`
 }
 
 type InputArray struct {
+	Name         string              `json:"name,omitempty"`
 	Label        string              `json:"label,omitempty"`
 	Items        []InputType         `json:"items,omitempty"`
 	InputBinding *CommandLineBinding `json:"inputBinding,omitempty"`
 }
 
 type OutputRecord struct {
+	Name   string        `json:"name,omitempty"`
 	Label  string        `json:"label,omitempty"`
 	Fields []OutputField `json:"fields,omitempty"`
 }
@@ -38,12 +42,14 @@ type OutputField struct {
 }
 
 type OutputEnum struct {
+	Name          string                `json:"name,omitempty"`
 	Label         string                `json:"label,omitempty"`
 	Symbols       []string              `json:"symbols,omitempty"`
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
 }
 
 type OutputArray struct {
+	Name          string                `json:"name,omitempty"`
 	Label         string                `json:"label,omitempty"`
 	Items         []OutputType          `json:"items,omitempty"`
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
@@ -32,7 +32,7 @@ type ScriptInput struct {
 
 	Type []InputType `json:"type,omitempty"`
 
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
 	Format         []Expression `json:"format,omitempty"`
 
 	InputBinding *CommandLineBinding `json:"inputBinding,omitempty"`
@@ -46,14 +46,18 @@ type ScriptOutput struct {
 
 	Type []OutputType `json:"type,omitempty"`
 
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
 	Format         []Expression `json:"format,omitempty"`
 
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
 }
 
+// CStep is one command of a multi-command tool.
 type CStep struct {
 	BaseCommand []string              `json:"baseCommand,omitempty"`
 	Arguments   []*CommandLineBinding `json:"arguments,omitempty"`
+	// Inputs lists the IDs of tool inputs whose input bindings
+	// are added to this step's command line.
+	Inputs []string `json:"inputs,omitempty"`
 }
 
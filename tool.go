@@ -5,7 +5,7 @@ type Tool struct {
 	ID         string `json:"id,omitempty"`
 	Label      string `json:"label,omitempty"`
 	Doc        string `json:"doc,omitempty"`
-	SourceFile string `json:"sourcefile,omitempty"`
+	SourceFile string `json:"-"`
 
 	Hints        []Requirement `json:"hints,omitempty"`
 	Requirements []Requirement `json:"requirements,omitempty"`
@@ -27,6 +27,8 @@ type Tool struct {
 	SuccessCodes       []int `json:"successCodes,omitempty"`
 	TemporaryFailCodes []int `json:",omitempty"`
 	PermanentFailCodes []int `json:",omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type CommandInput struct {
@@ -38,10 +40,15 @@ type CommandInput struct {
 
 	Type []InputType `json:"type,omitempty"`
 
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
 	Format         []Expression `json:"format,omitempty"`
 
 	InputBinding *CommandLineBinding `json:"inputBinding,omitempty"`
+
+	LoadContents bool              `json:"loadContents,omitempty" cwl:"v1.1"`
+	LoadListing  LoadListingMethod `json:"loadListing,omitempty" cwl:"v1.1"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type CommandOutput struct {
@@ -52,15 +59,18 @@ type CommandOutput struct {
 
 	Type []OutputType `json:"type,omitempty"`
 
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
 	Format         []Expression `json:"format,omitempty"`
 
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type CommandLineBinding struct {
 	LoadContents  bool       `json:"loadContents,omitempty"`
-	Position      int        `json:"position,omitempty"`
+	// Position is an int, or since v1.1, an expression.
+	Position      Expression `json:"position,omitempty"`
 	Prefix        string     `json:"prefix,omitempty"`
 	ItemSeparator string     `json:"itemSeparator,omitempty"`
 	ValueFrom     Expression `json:"valueFrom,omitempty"`
@@ -71,5 +81,6 @@ type CommandLineBinding struct {
 type CommandOutputBinding struct {
 	Glob         []Expression `json:"glob,omitempty"`
 	LoadContents bool         `json:"loadContents,omitempty"`
+	LoadListing  LoadListingMethod `json:"loadListing,omitempty" cwl:"v1.1"`
 	OutputEval   Expression   `json:"outputEval,omitempty"`
 }
@@ -0,0 +1,276 @@
+package cwl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change describes a change made to a document by Upgrade.
+type Change struct {
+	// Path is the dotted path to the changed field, such as "inputs.reads".
+	Path    string
+	Message string
+}
+
+func (c Change) String() string {
+	if c.Path == "" {
+		return c.Message
+	}
+	return c.Path + ": " + c.Message
+}
+
+// Upgrade converts a document, and the documents embedded in it,
+// to a later version of CWL, such as "v1.2", and returns the upgraded
+// document and the changes made. Documents are changed in place, except
+// for a $graph, which is returned as a copy. If "to" is empty, the document
+// is upgraded to LatestVersion.
+//
+// Since v1.0 documents get behavior which later versions made optional,
+// the upgrade adds the requirements which keep that behavior:
+// NetworkAccess for command line tools, and a deep LoadListingRequirement
+// for documents with Directory inputs. Secondary file patterns are
+// converted to pattern objects, with "required" made explicit.
+//
+// Referenced documents (DocumentRef) aren't upgraded; load the document
+// with NoResolve to keep references as they are.
+func Upgrade(doc Document, to string) (Document, []Change, error) {
+	if to == "" {
+		to = LatestVersion
+	}
+	if !isVersion(to) {
+		return nil, nil, errf("unsupported cwlVersion %q, expected one of %s",
+			to, strings.Join(cwlVersions, ", "))
+	}
+	if g, ok := doc.(Graph); ok {
+		doc = &g
+	}
+	u := upgrader{to: to}
+	if err := u.doc(doc, "", ""); err != nil {
+		return nil, nil, err
+	}
+	if g, ok := doc.(*Graph); ok {
+		doc = *g
+	}
+	return doc, u.changes, nil
+}
+
+type upgrader struct {
+	to      string
+	changes []Change
+}
+
+func (u *upgrader) change(path, msg string, args ...interface{}) {
+	u.changes = append(u.changes, Change{Path: path, Message: fmt.Sprintf(msg, args...)})
+}
+
+// doc upgrades a document found at "path". "from" is the version of the
+// parent document, which applies if the document doesn't have its own.
+func (u *upgrader) doc(doc Document, path, from string) error {
+	var version *string
+	switch z := doc.(type) {
+	case *Tool:
+		version = &z.CWLVersion
+	case *Workflow:
+		version = &z.CWLVersion
+	case *ExpressionTool:
+		version = &z.CWLVersion
+	case *Operation:
+		version = &z.CWLVersion
+	case *Script:
+		version = &z.CWLVersion
+	case *Graph:
+		version = &z.CWLVersion
+	case DocumentRef:
+		u.change(path, "referenced document %s was not upgraded", z.Location)
+		return nil
+	default:
+		return errf("%s: can't upgrade a %s document", path, doc.Doctype())
+	}
+
+	if *version != "" {
+		from = *version
+	}
+	if from == "" {
+		return errf("%s: missing cwlVersion", pathOrRoot(path))
+	}
+	idx := versionIndex(from)
+	if idx == -1 {
+		return errf("%s: unsupported cwlVersion %q", pathOrRoot(path), from)
+	}
+	if idx >= versionIndex(u.to) {
+		return nil
+	}
+	if *version != "" {
+		u.change(join(path, "cwlVersion"), "changed from %s to %s", from, u.to)
+		*version = u.to
+	}
+
+	// Only v1.0 needs converting, later versions are compatible.
+	v10 := idx < versionIndex("v1.1")
+
+	switch z := doc.(type) {
+	case *Graph:
+		for _, d := range z.Docs {
+			id := docID(d)
+			if id == "" {
+				id = d.Doctype()
+			}
+			if err := u.doc(d, join(path, "$graph."+id), from); err != nil {
+				return err
+			}
+		}
+
+	case *Tool:
+		if !v10 {
+			return nil
+		}
+		var dirs bool
+		for i := range z.Inputs {
+			in := &z.Inputs[i]
+			u.secondaryFiles(in.SecondaryFiles, join(path, "inputs."+in.ID), "true")
+			dirs = dirs || hasDirectory(in.Type)
+		}
+		for i := range z.Outputs {
+			out := &z.Outputs[i]
+			u.secondaryFiles(out.SecondaryFiles, join(path, "outputs."+out.ID), "false")
+		}
+		if dirs {
+			u.loadListing(&z.Requirements, z.Hints, path)
+		}
+		if !hasRequirement(z.Requirements, z.Hints, "NetworkAccess") {
+			// v1.0 didn't restrict network access.
+			z.Requirements = append(z.Requirements, NetworkAccess{NetworkAccess: "true"})
+			u.change(join(path, "requirements"), "added NetworkAccess, since v1.0 tools could always access the network")
+		}
+
+	case *ExpressionTool:
+		if !v10 {
+			return nil
+		}
+		var dirs bool
+		for i := range z.Inputs {
+			in := &z.Inputs[i]
+			u.secondaryFiles(in.SecondaryFiles, join(path, "inputs."+in.ID), "true")
+			dirs = dirs || hasDirectory(in.Type)
+		}
+		for i := range z.Outputs {
+			out := &z.Outputs[i]
+			u.secondaryFiles(out.SecondaryFiles, join(path, "outputs."+out.ID), "false")
+		}
+		if dirs {
+			u.loadListing(&z.Requirements, z.Hints, path)
+		}
+
+	case *Workflow:
+		var dirs bool
+		for i := range z.Inputs {
+			in := &z.Inputs[i]
+			if v10 {
+				u.secondaryFiles(in.SecondaryFiles, join(path, "inputs."+in.ID), "true")
+			}
+			dirs = dirs || hasDirectory(in.Type)
+		}
+		for i := range z.Outputs {
+			out := &z.Outputs[i]
+			if v10 {
+				u.secondaryFiles(out.SecondaryFiles, join(path, "outputs."+out.ID), "false")
+			}
+		}
+		if v10 && dirs {
+			u.loadListing(&z.Requirements, z.Hints, path)
+		}
+		for _, step := range z.Steps {
+			if step.Run == nil {
+				continue
+			}
+			if err := u.doc(step.Run, join(path, "steps."+step.ID+".run"), from); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// secondaryFiles converts secondary file patterns to pattern objects,
+// by setting "required" to the default for the field.
+func (u *upgrader) secondaryFiles(sfs []SecondaryFile, path, required string) {
+	for i := range sfs {
+		if sfs[i].Required != "" {
+			continue
+		}
+		sfs[i].Required = Expression(required)
+		u.change(join(path, "secondaryFiles"), "converted %q to a pattern object with required: %s",
+			sfs[i].Pattern, required)
+	}
+}
+
+// loadListing adds a LoadListingRequirement, which keeps
+// the v1.0 behavior of listing directories deeply.
+func (u *upgrader) loadListing(reqs *[]Requirement, hints []Requirement, path string) {
+	if hasRequirement(*reqs, hints, "LoadListingRequirement") {
+		return
+	}
+	*reqs = append(*reqs, LoadListingRequirement{LoadListing: DeepListing})
+	u.change(join(path, "requirements"), "added LoadListingRequirement with deep_listing, the v1.0 behavior")
+}
+
+func hasRequirement(reqs, hints []Requirement, class string) bool {
+	for _, r := range append(append([]Requirement{}, reqs...), hints...) {
+		if strings.EqualFold(RequirementClass(r), class) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirectory returns true if any of the types is,
+// or contains, a Directory.
+func hasDirectory(types []InputType) bool {
+	for _, t := range types {
+		switch z := t.(type) {
+		case DirectoryType:
+			return true
+		case InputArray:
+			if hasDirectory(z.Items) {
+				return true
+			}
+		case InputRecord:
+			for _, f := range z.Fields {
+				if hasDirectory(f.Type) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func docID(d Document) string {
+	switch z := d.(type) {
+	case *Tool:
+		return z.ID
+	case *Workflow:
+		return z.ID
+	case *ExpressionTool:
+		return z.ID
+	case *Operation:
+		return z.ID
+	case *Script:
+		return z.ID
+	}
+	return ""
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+package cwl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpgrade(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: Workflow
+inputs:
+  - id: ref
+    type: File
+    secondaryFiles: [.fai, ^.dict]
+  - id: dbs
+    type: {type: array, items: Directory}
+outputs: []
+steps:
+  - id: index
+    run:
+      class: CommandLineTool
+      inputs:
+        - id: ref
+          type: File
+          secondaryFiles: [.fai]
+      outputs:
+        - id: bam
+          type: File
+          secondaryFiles: [.bai]
+      baseCommand: index
+    in: {ref: ref}
+    out: [bam]
+  - id: expr
+    run:
+      class: ExpressionTool
+      inputs:
+        - id: ref
+          type: File
+          secondaryFiles: [.fai]
+      outputs:
+        - id: out
+          type: File
+          secondaryFiles: [.idx]
+      expression: "$({out: inputs.ref})"
+    in: {ref: ref}
+    out: [out]
+  - id: other
+    run: other.cwl
+    in: []
+    out: []
+`
+	d, err := LoadDocumentBytes([]byte(doc), ".", NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, changes, err := Upgrade(d, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var summary []string
+	for _, c := range changes {
+		summary = append(summary, c.String())
+	}
+	expect := []string{
+		"cwlVersion: changed from v1.0 to v1.2",
+		`inputs.ref.secondaryFiles: converted ".fai" to a pattern object with required: true`,
+		`inputs.ref.secondaryFiles: converted "^.dict" to a pattern object with required: true`,
+		"requirements: added LoadListingRequirement with deep_listing, the v1.0 behavior",
+		`steps.index.run.inputs.ref.secondaryFiles: converted ".fai" to a pattern object with required: true`,
+		`steps.index.run.outputs.bam.secondaryFiles: converted ".bai" to a pattern object with required: false`,
+		"steps.index.run.requirements: added NetworkAccess, since v1.0 tools could always access the network",
+		`steps.expr.run.inputs.ref.secondaryFiles: converted ".fai" to a pattern object with required: true`,
+		`steps.expr.run.outputs.out.secondaryFiles: converted ".idx" to a pattern object with required: false`,
+		"steps.other.run: referenced document other.cwl was not upgraded",
+	}
+	if !reflect.DeepEqual(summary, expect) {
+		t.Errorf("unexpected changes:\n%s", strings.Join(summary, "\n"))
+	}
+
+	// The upgraded document loads as v1.2 without warnings.
+	b, err := MarshalYAML(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, warnings, err := LoadDocumentBytesWithOptions(b, ".", LoadOptions{Resolver: NoResolve(), Strict: true})
+	if err != nil {
+		t.Fatalf("reloading upgraded document: %s\n%s", err, b)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if !reflect.DeepEqual(d, d2) {
+		t.Errorf("upgraded document didn't reload:\n%s", b)
+	}
+
+	// Upgrading again changes nothing.
+	_, changes, err = Upgrade(d2, "v1.2")
+	if err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %v %v", changes, err)
+	}
+
+	if _, _, err := Upgrade(d2, "v2.0"); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
@@ -49,9 +49,10 @@ func dump(n *yamlast.Node, indent string) {
 
 // used for finding a value such as "class: Workflow" in a YAML mapping,
 // which is needed before document processing can begin.
+// A node which is not a mapping has no keys, so nothing is found.
 func findValue(n node, key string) (node, bool) {
 	if n.Kind != yamlast.MappingNode {
-		panic("findValue requires a mapping node")
+		return nil, false
 	}
 	for i := 0; i < len(n.Children)-1; i += 2 {
 		k := n.Children[i]
@@ -77,15 +78,18 @@ type mapitem struct {
 
 // itermap turns a YAML mapping into a slice of key/value pairs.
 // a YAML mapping is a slice of [key1, value1, key2, value2, etc...]
-func itermap(n node) []mapitem {
+func itermap(n node) ([]mapitem, error) {
 	items := []mapitem{}
 	if n.Kind != yamlast.MappingNode {
-		panic("expected mapping node")
+		return nil, errf("expected a mapping")
+	}
+	if len(n.Children)%2 != 0 {
+		return nil, errf("expected even number of children in mapping")
 	}
 	for i := 0; i < len(n.Children)-1; i += 2 {
 		k := n.Children[i]
 		v := n.Children[i+1]
 		items = append(items, mapitem{k.Value, v})
 	}
-	return items
+	return items, nil
 }
@@ -38,7 +38,11 @@ func (l *loader) MappingToValue(n node) (Value, error) {
 	}
 
 	vals := map[string]Value{}
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 
@@ -54,7 +58,11 @@ func (l *loader) MappingToValue(n node) (Value, error) {
 
 func (l *loader) MappingToValueMap(n node) (map[string]Value, error) {
 	vals := Values{}
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		var a Value
@@ -66,3 +74,17 @@ func (l *loader) MappingToValueMap(n node) (map[string]Value, error) {
 	}
 	return vals, nil
 }
+
+// MappingToFileDir loads a secondary file or a directory listing entry
+// of an input value.
+func (l *loader) MappingToFileDir(n node) (FileDir, error) {
+	v, err := l.MappingToValue(n)
+	if err != nil {
+		return nil, err
+	}
+	fd, ok := v.(FileDir)
+	if !ok {
+		return nil, l.errorAt(n, "expected a File or Directory")
+	}
+	return fd, nil
+}
@@ -0,0 +1,78 @@
+package cwl
+
+import (
+	"strings"
+)
+
+// cwlVersions are the versions of CWL which can be loaded, oldest first.
+var cwlVersions = []string{"v1.0", "v1.1", LatestVersion}
+
+// LatestVersion is the newest version of CWL which can be loaded.
+const LatestVersion = "v1.2"
+
+// classVersions are the versions which added document
+// and requirement classes, by lowercase class name.
+var classVersions = map[string]string{
+	"operation":                "v1.2",
+	"tooltimelimit":            "v1.1",
+	"loadlistingrequirement":   "v1.1",
+	"networkaccess":            "v1.1",
+	"workreuse":                "v1.1",
+	"inplaceupdaterequirement": "v1.1",
+}
+
+// versionIndex returns the index of a version in cwlVersions,
+// or -1 if it's unknown. Suffixes such as "v1.0.dev4" are ignored.
+func versionIndex(v string) int {
+	for i, known := range cwlVersions {
+		if v == known || strings.HasPrefix(v, known+".") {
+			return i
+		}
+	}
+	return -1
+}
+
+// isVersion returns true if "v" is exactly one of cwlVersions.
+func isVersion(v string) bool {
+	for _, known := range cwlVersions {
+		if v == known {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDocVersion warns if the "cwlVersion" of a document is unknown.
+func (l *loader) checkDocVersion(root node) error {
+	v, ok := findValue(root, "cwlVersion")
+	if !ok {
+		return nil
+	}
+	l.version = v.Value
+	if versionIndex(l.version) == -1 {
+		return l.warn(v, "unsupported cwlVersion %q, expected one of %s",
+			l.version, strings.Join(cwlVersions, ", "))
+	}
+	return nil
+}
+
+// checkVersion warns if "what", found at node "n", was added
+// in a later version of CWL than the document's version.
+// Documents without a known version aren't checked.
+func (l *loader) checkVersion(n node, since, what string) error {
+	have := versionIndex(l.version)
+	if have == -1 || have >= versionIndex(since) {
+		return nil
+	}
+	return l.warn(n, "%s requires cwlVersion %s or later, but the document is %s",
+		what, since, l.version)
+}
+
+// checkClassVersion checks the version of a document or requirement class.
+func (l *loader) checkClassVersion(n node, class string) error {
+	since, ok := classVersions[strings.ToLower(class)]
+	if !ok {
+		return nil
+	}
+	return l.checkVersion(n, since, class)
+}
@@ -0,0 +1,154 @@
+package cwl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	docs := map[string]string{
+		"tool": `
+cwlVersion: v1.2
+class: CommandLineTool
+requirements:
+  - class: LoadListingRequirement
+    loadListing: shallow_listing
+  - class: NetworkAccess
+    networkAccess: true
+  - class: WorkReuse
+    enableReuse: $(inputs.reuse)
+  - class: InplaceUpdateRequirement
+    inplaceUpdate: true
+  - class: ToolTimeLimit
+    timelimit: 60
+inputs:
+  - id: reuse
+    type: boolean
+  - id: reads
+    type: File
+    loadContents: true
+    secondaryFiles:
+      - .bai
+      - pattern: .crai
+        required: false
+    inputBinding:
+      position: $(self.size)
+outputs: []
+`,
+		"workflow": `
+cwlVersion: v1.2
+class: Workflow
+inputs:
+  - id: dir
+    type: Directory
+    loadListing: deep_listing
+outputs:
+  - id: out
+    type: File
+    outputSource: [a/out, b/out]
+    pickValue: first_non_null
+steps:
+  - id: a
+    run: "#tool"
+    when: $(inputs.dir != null)
+    in:
+      - id: dir
+        source: [dir]
+        pickValue: all_non_null
+    out: [out]
+  - id: b
+    run: "#tool"
+    in: []
+    out: [out]
+`,
+		"operation": `
+cwlVersion: v1.2
+class: Operation
+inputs:
+  - id: x
+    type: int
+outputs:
+  - id: y
+    type: int
+`,
+	}
+	for name, doc := range docs {
+		d, warnings, err := LoadDocumentBytesWithOptions([]byte(doc), ".",
+			LoadOptions{Resolver: NoResolve(), Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("%s: unexpected warnings: %v", name, warnings)
+		}
+		checkRoundTrip(t, name, d, ".", NoResolve(), 0)
+	}
+
+	d, _ := LoadDocumentBytes([]byte(docs["tool"]), ".", nil)
+	in := d.(*Tool).Inputs[1]
+	if len(in.SecondaryFiles) != 2 || in.SecondaryFiles[1].Required != "false" {
+		t.Errorf("unexpected secondary files: %#v", in.SecondaryFiles)
+	}
+	if in.InputBinding.Position != "$(self.size)" {
+		t.Errorf("unexpected position: %#v", in.InputBinding.Position)
+	}
+
+	d, _, _ = LoadDocumentBytesWithOptions([]byte(docs["workflow"]), ".",
+		LoadOptions{Resolver: NoResolve()})
+	wf := d.(*Workflow)
+	if wf.Steps[0].When != "$(inputs.dir != null)" {
+		t.Errorf("unexpected when: %#v", wf.Steps[0].When)
+	}
+	if wf.Outputs[0].PickValue != FirstNonNull || wf.Steps[0].In[0].PickValue != AllNonNull {
+		t.Errorf("unexpected pickValue")
+	}
+}
+
+func TestVersionWarnings(t *testing.T) {
+	doc := `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+requirements:
+  - class: NetworkAccess
+    networkAccess: true
+steps:
+  - id: a
+    run: tool.cwl
+    when: $(true)
+    in: []
+    out: []
+`
+	_, warnings, err := LoadDocumentBytesWithOptions([]byte(doc), ".",
+		LoadOptions{Resolver: NoResolve()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if w := warnings[0]; w.Line != 7 || !strings.Contains(w.Error(), "NetworkAccess requires cwlVersion v1.1") {
+		t.Errorf("unexpected warning: %s", w)
+	}
+	if w := warnings[1]; w.Line != 12 || !strings.Contains(w.Error(), `field "when" requires cwlVersion v1.2`) {
+		t.Errorf("unexpected warning: %s", w)
+	}
+
+	_, _, err = LoadDocumentBytesWithOptions([]byte(doc), ".",
+		LoadOptions{Resolver: NoResolve(), Strict: true})
+	if _, ok := err.(*LoadError); !ok {
+		t.Errorf("expected *LoadError, got %T: %v", err, err)
+	}
+
+	_, warnings, _ = LoadDocumentBytesWithOptions([]byte(`
+cwlVersion: v2.0
+class: Workflow
+inputs: []
+outputs: []
+steps: []
+`), ".", LoadOptions{})
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "unsupported cwlVersion") {
+		t.Errorf("expected unsupported version warning, got %v", warnings)
+	}
+}
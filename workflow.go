@@ -1,12 +1,12 @@
 package cwl
 
 type Workflow struct {
-	Gid 		int64 `xorm:"pk"`
+	Gid 		int64 `xorm:"pk" json:"-"`
 	CWLVersion string `json:"cwlVersion,omitempty"`
 	ID         string `json:"id,omitempty"`
 	Label      string `json:"label,omitempty"`
 	Doc        string `json:"doc,omitempty"`
-	SourceFile string `json:"sourcefile,omitempty"`
+	SourceFile string `json:"-"`
 
 	Hints        []Requirement `json:"hints,omitempty"`
 	Requirements []Requirement `json:"requirements,omitempty"`
@@ -14,6 +14,8 @@ type Workflow struct {
 	Inputs  []WorkflowInput  `json:"inputs,omitempty"`
 	Outputs []WorkflowOutput `json:"outputs,omitempty"`
 	Steps   []Step           `json:"steps,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
 
 // TODO exactly the same as CommandInput? Changing in v1.1?
@@ -26,10 +28,15 @@ type WorkflowInput struct {
 
 	Type           []InputType         `json:"type,omitempty"`
 
-	SecondaryFiles []Expression        `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile      `json:"secondaryFiles,omitempty"`
 	Format         []Expression        `json:"format,omitempty"`
 
 	InputBinding   *CommandLineBinding `json:"inputBinding,omitempty"`
+
+	LoadContents bool              `json:"loadContents,omitempty" cwl:"v1.1"`
+	LoadListing  LoadListingMethod `json:"loadListing,omitempty" cwl:"v1.1"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type WorkflowOutput struct {
@@ -40,11 +47,14 @@ type WorkflowOutput struct {
 	LinkMerge  LinkMergeMethod `json:"linkMerge,omitempty"`
 
 	Type           []OutputType `json:"type,omitempty"`
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
 	Format         []Expression `json:"format,omitempty"`
 
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
 	OutputSource  []string              `json:"outputSource,omitempty"`
+	PickValue     PickValueMethod       `json:"pickValue,omitempty" cwl:"v1.2"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type Step struct {
@@ -62,6 +72,11 @@ type Step struct {
 
 	Scatter       []string      `json:"scatter,omitempty"`
 	ScatterMethod ScatterMethod `json:"scatterMethod,omitempty"`
+
+	// When is a condition for running the step, added in v1.2.
+	When Expression `json:"when,omitempty" cwl:"v1.2"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type StepInput struct {
@@ -70,8 +85,16 @@ type StepInput struct {
 	LinkMerge LinkMergeMethod `json:"linkMerge,omitempty"`
 	Default   Value           `json:"default,omitempty"`
 	ValueFrom Expression      `json:"valueFrom,omitempty"`
+	PickValue PickValueMethod `json:"pickValue,omitempty" cwl:"v1.2"`
+
+	LoadContents bool              `json:"loadContents,omitempty" cwl:"v1.1"`
+	LoadListing  LoadListingMethod `json:"loadListing,omitempty" cwl:"v1.1"`
+
+	Extensions Extensions `json:"-"`
 }
 
 type StepOutput struct {
 	ID string `json:"id,omitempty"`
+
+	Extensions Extensions `json:"-"`
 }
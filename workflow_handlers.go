@@ -1,14 +1,17 @@
 package cwl
 
 import (
-	"fmt"
 	"github.com/commondream/yamlast"
 )
 
 func (l *loader) MappingToWorkflowInputSlice(n node) ([]WorkflowInput, error) {
 	var inputs []WorkflowInput
 
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		i := WorkflowInput{ID: k}
@@ -26,8 +29,7 @@ func (l *loader) MappingToWorkflowInputSlice(n node) ([]WorkflowInput, error) {
 				return nil, err
 			}
 		default:
-			// TODO want errors to return position information
-			return nil, fmt.Errorf("invalid yaml node type for workflow input")
+			return nil, l.errorAt(v, "invalid yaml node type for workflow input")
 		}
 
 		inputs = append(inputs, i)
@@ -39,7 +41,11 @@ func (l *loader) MappingToWorkflowInputSlice(n node) ([]WorkflowInput, error) {
 func (l *loader) MappingToWorkflowOutputSlice(n node) ([]WorkflowOutput, error) {
 	var outputs []WorkflowOutput
 
-	for _, kv := range itermap(n) {
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		o := WorkflowOutput{ID: k}
@@ -58,8 +64,7 @@ func (l *loader) MappingToWorkflowOutputSlice(n node) ([]WorkflowOutput, error)
 				return nil, err
 			}
 		default:
-			// TODO want errors to return position information
-			return nil, fmt.Errorf("invalid yaml node type for workflow output")
+			return nil, l.errorAt(v, "invalid yaml node type for workflow output")
 		}
 		outputs = append(outputs, o)
 	}
@@ -68,8 +73,12 @@ func (l *loader) MappingToWorkflowOutputSlice(n node) ([]WorkflowOutput, error)
 }
 
 func (l *loader) MappingToStepSlice(n node) ([]Step, error) {
-	steps := []Step{}
-	for _, kv := range itermap(n) {
+	var steps []Step
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		step := Step{}
@@ -88,7 +97,7 @@ func (l *loader) ScalarToStepOutput(n node) (StepOutput, error) {
 }
 
 func (l *loader) SeqToStepInputSlice(n node) ([]StepInput, error) {
-	ins := []StepInput{}
+	var ins []StepInput
 	for _, c := range n.Children {
 		in := StepInput{}
 		err := l.load(c, &in)
@@ -102,7 +111,7 @@ func (l *loader) SeqToStepInputSlice(n node) ([]StepInput, error) {
 }
 
 func (l *loader) SeqToStepOutputSlice(n node) ([]StepOutput, error) {
-	outs := []StepOutput{}
+	var outs []StepOutput
 	for _, c := range n.Children {
 		out := StepOutput{}
 		err := l.load(c, &out)
@@ -115,8 +124,12 @@ func (l *loader) SeqToStepOutputSlice(n node) ([]StepOutput, error) {
 }
 
 func (l *loader) MappingToStepInputSlice(n node) ([]StepInput, error) {
-	ins := []StepInput{}
-	for _, kv := range itermap(n) {
+	var ins []StepInput
+	items, err := itermap(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range items {
 		k := kv.k
 		v := kv.v
 		in := StepInput{ID: k}
@@ -132,8 +145,7 @@ func (l *loader) MappingToStepInputSlice(n node) ([]StepInput, error) {
 		case yamlast.ScalarNode:
 			in.Source = []string{v.Value}
 		default:
-			// TODO want errors to return position information
-			return nil, fmt.Errorf("invalid yaml node type for step input")
+			return nil, l.errorAt(v, "invalid yaml node type for step input")
 		}
 
 		ins = append(ins, in)
@@ -0,0 +1,271 @@
+package cwl
+
+import (
+	"bytes"
+	"github.com/commondream/yamlast"
+)
+
+// maxAliasNodes limits the number of nodes created by expanding aliases,
+// to protect against documents which expand exponentially ("billion laughs").
+const maxAliasNodes = 1000000
+
+// parseYAML parses a YAML stream into the root node of each document in it,
+// with aliases and merge keys ("<<") resolved. Empty documents are skipped.
+// "file" is the location of the stream, used in errors.
+func parseYAML(b []byte, file string) ([]node, error) {
+	var docs []node
+
+	for _, chunk := range splitYAMLDocuments(b) {
+		yamlnode, err := yamlast.Parse(chunk.b)
+		if err != nil {
+			e := parseError(file, err).(*LoadError)
+			if e.Line > 0 {
+				e.Line += chunk.line
+			}
+			return nil, e
+		}
+		if yamlnode == nil || len(yamlnode.Children) == 0 {
+			continue
+		}
+		if len(yamlnode.Children) > 1 {
+			return nil, &LoadError{File: file, Line: chunk.line + 1, Err: errf("unexpected child count")}
+		}
+
+		root := node(yamlnode.Children[0])
+		shiftLines(root, chunk.line)
+
+		r := aliasResolver{file: file, anchors: yamlnode.Anchors, active: map[*yamlast.Node]bool{}}
+		root, err = r.expand(root)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveMergeKeys(root, file); err != nil {
+			return nil, err
+		}
+		docs = append(docs, root)
+	}
+	return docs, nil
+}
+
+type yamlChunk struct {
+	b []byte
+	// line is the 0-based line of the stream where the chunk starts.
+	line int
+}
+
+// splitYAMLDocuments splits a YAML stream into documents, at the
+// "---" and "..." markers. The YAML spec doesn't allow these markers
+// at the start of a line inside a document's content, so there's no
+// need to parse the stream to find them.
+//
+// Directives, such as "%YAML 1.1" or "%TAG", belong to the document
+// which follows them, so they're kept in that document's chunk,
+// along with its "---" marker, which the parser then requires.
+func splitYAMLDocuments(b []byte) []yamlChunk {
+	var chunks []yamlChunk
+	var cur yamlChunk
+	var buf bytes.Buffer
+	// directives is true if the lines since the last marker are directives,
+	// which can only appear at the start of the stream or after "...".
+	directives := false
+	betweenDocs := true
+
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	for i, line := range lines {
+		start := isDocMarker(line, "---")
+		end := isDocMarker(line, "...")
+		if !start && !end {
+			if betweenDocs && bytes.HasPrefix(line, []byte("%")) {
+				directives = true
+			} else if !isBlankOrComment(line) {
+				directives = false
+				betweenDocs = false
+			}
+			buf.Write(line)
+			continue
+		}
+
+		if start && directives {
+			// Keep the directives with the document they apply to.
+			buf.Write(line)
+			directives = false
+			betweenDocs = false
+			continue
+		}
+
+		cur.b = append([]byte{}, buf.Bytes()...)
+		chunks = append(chunks, cur)
+		buf.Reset()
+		cur = yamlChunk{line: i + 1}
+		directives = false
+		betweenDocs = end
+
+		if start {
+			// Content may follow the marker on the same line, e.g. "--- |".
+			// Blank out the marker to keep the columns of the content.
+			buf.WriteString("   ")
+			buf.Write(line[3:])
+			cur.line = i
+		}
+	}
+	cur.b = buf.Bytes()
+	chunks = append(chunks, cur)
+	return chunks
+}
+
+// isBlankOrComment returns true for a line with only
+// whitespace, or a comment.
+func isBlankOrComment(line []byte) bool {
+	t := bytes.TrimSpace(line)
+	return len(t) == 0 || t[0] == '#'
+}
+
+func isDocMarker(line []byte, marker string) bool {
+	if !bytes.HasPrefix(line, []byte(marker)) {
+		return false
+	}
+	rest := line[len(marker):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r'
+}
+
+func shiftLines(n *yamlast.Node, offset int) {
+	n.Line += offset
+	for _, c := range n.Children {
+		shiftLines(c, offset)
+	}
+}
+
+// aliasResolver replaces alias nodes with a copy of the node
+// their anchor refers to.
+type aliasResolver struct {
+	file    string
+	anchors map[string]*yamlast.Node
+	// active holds the anchored nodes being expanded,
+	// in order to detect recursive aliases.
+	active map[*yamlast.Node]bool
+	count  int
+}
+
+func (r *aliasResolver) expand(n node) (node, error) {
+	if n.Kind == yamlast.AliasNode {
+		target, ok := r.anchors[n.Value]
+		if !ok {
+			return nil, yamlError(r.file, n, "unknown anchor %q", n.Value)
+		}
+		if r.active[target] {
+			return nil, yamlError(r.file, n, "recursive alias %q", n.Value)
+		}
+		r.active[target] = true
+		defer delete(r.active, target)
+
+		c, err := r.copy(target)
+		if err != nil {
+			return nil, yamlError(r.file, n, "%s", err)
+		}
+		return r.expand(c)
+	}
+
+	for i, c := range n.Children {
+		x, err := r.expand(c)
+		if err != nil {
+			return nil, err
+		}
+		n.Children[i] = x
+	}
+	return n, nil
+}
+
+// copy makes a deep copy of a node, because later processing
+// may modify nodes, and an anchor may be aliased many times.
+func (r *aliasResolver) copy(n *yamlast.Node) (*yamlast.Node, error) {
+	r.count++
+	if r.count > maxAliasNodes {
+		return nil, errf("document is too large after expanding aliases")
+	}
+	c := *n
+	c.Children = nil
+	for _, child := range n.Children {
+		x, err := r.copy(child)
+		if err != nil {
+			return nil, err
+		}
+		c.Children = append(c.Children, x)
+	}
+	return &c, nil
+}
+
+// resolveMergeKeys resolves merge keys ("<<") in mappings.
+// Keys in the mapping take precedence over merged keys; when merging
+// a list of mappings, keys from earlier mappings take precedence.
+//
+// https://yaml.org/type/merge.html
+func resolveMergeKeys(n node, file string) error {
+	for _, c := range n.Children {
+		if err := resolveMergeKeys(c, file); err != nil {
+			return err
+		}
+	}
+	if n.Kind != yamlast.MappingNode || len(n.Children)%2 != 0 {
+		return nil
+	}
+
+	hasMerge := false
+	explicit := map[string]bool{}
+	for i := 0; i < len(n.Children); i += 2 {
+		k := n.Children[i]
+		if isMergeKey(k) {
+			hasMerge = true
+		} else {
+			explicit[k.Value] = true
+		}
+	}
+	if !hasMerge {
+		return nil
+	}
+
+	var children []*yamlast.Node
+	merged := map[string]bool{}
+	for i := 0; i < len(n.Children); i += 2 {
+		k := n.Children[i]
+		v := n.Children[i+1]
+		if !isMergeKey(k) {
+			children = append(children, k, v)
+			continue
+		}
+
+		sources := []*yamlast.Node{v}
+		if v.Kind == yamlast.SequenceNode {
+			sources = v.Children
+		}
+		for _, src := range sources {
+			if src.Kind != yamlast.MappingNode || len(src.Children)%2 != 0 {
+				return yamlError(file, src, "merge key value must be a mapping or a list of mappings")
+			}
+			for j := 0; j < len(src.Children); j += 2 {
+				sk := src.Children[j]
+				if explicit[sk.Value] || merged[sk.Value] {
+					continue
+				}
+				merged[sk.Value] = true
+				children = append(children, sk, src.Children[j+1])
+			}
+		}
+	}
+	n.Children = children
+	return nil
+}
+
+// isMergeKey returns true for an unquoted "<<" key;
+// a quoted "<<" is a plain string.
+func isMergeKey(k *yamlast.Node) bool {
+	return k.Kind == yamlast.ScalarNode && k.Value == "<<" && (k.Implicit || k.Tag == "!!merge")
+}
+
+func yamlError(file string, n node, msg string, args ...interface{}) error {
+	return &LoadError{
+		File:   file,
+		Line:   n.Line + 1,
+		Column: n.Column + 1,
+		Err:    errf(msg, args...),
+	}
+}
@@ -0,0 +1,146 @@
+package cwl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadValuesAliases(t *testing.T) {
+	job := `
+defaults: &defaults
+  reference: hg38
+  threads: 4
+samples:
+  - <<: *defaults
+    name: a
+  - <<: *defaults
+    name: b
+    threads: 8
+  - <<: [{threads: 2}, *defaults]
+    name: c
+reads: &reads [x.fq, y.fq]
+more: *reads
+`
+	v, err := LoadValuesBytes([]byte(job))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := v["samples"].([]Value)
+	expect := []Value{
+		map[string]Value{"reference": "hg38", "threads": "4", "name": "a"},
+		map[string]Value{"reference": "hg38", "threads": "8", "name": "b"},
+		map[string]Value{"reference": "hg38", "threads": "2", "name": "c"},
+	}
+	if !reflect.DeepEqual(samples, expect) {
+		t.Errorf("unexpected merged samples: %#v", samples)
+	}
+	if !reflect.DeepEqual(v["more"], v["reads"]) {
+		t.Errorf("expected alias to copy anchored value, got %#v", v["more"])
+	}
+}
+
+func TestRecursiveAlias(t *testing.T) {
+	_, err := LoadValuesBytes([]byte("a: &a [1, *a]\n"))
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.Line != 1 {
+		t.Errorf("unexpected line %d", e.Line)
+	}
+}
+
+func TestQuotedMergeKey(t *testing.T) {
+	v, err := LoadValuesBytes([]byte(`
+defaults: &defaults {threads: 4}
+merged:
+  <<: *defaults
+quoted:
+  "<<": *defaults
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v["merged"], map[string]Value{"threads": "4"}) {
+		t.Errorf("expected the defaults to be merged, got %#v", v["merged"])
+	}
+	expect := map[string]Value{"<<": map[string]Value{"threads": "4"}}
+	if !reflect.DeepEqual(v["quoted"], expect) {
+		t.Errorf("expected a quoted \"<<\" to be a plain key, got %#v", v["quoted"])
+	}
+}
+
+func TestLoadAllValues(t *testing.T) {
+	stream := `
+# leading comment
+---
+sample: a
+---
+sample: b
+...
+---
+sample: c
+bad: "unterminated
+`
+	_, err := LoadAllValuesBytes([]byte(stream))
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	// The error is in the last document, which starts on line 8.
+	if e.Line < 9 {
+		t.Errorf("expected error in the last document, got line %d", e.Line)
+	}
+
+	stream = stream[:len(stream)-len("bad: \"unterminated\n")]
+	all, err := LoadAllValuesBytes([]byte(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Value
+	for _, v := range all {
+		got = append(got, v["sample"])
+	}
+	if !reflect.DeepEqual(got, []Value{"a", "b", "c"}) {
+		t.Errorf("unexpected documents: %v", got)
+	}
+
+	if _, err := LoadValuesBytes([]byte(stream)); err == nil {
+		t.Error("expected error loading a multi-document stream as a single document")
+	}
+}
+
+func TestLoadAllValuesDirectives(t *testing.T) {
+	stream := `%YAML 1.1
+---
+sample: a
+...
+# the directive applies to the next document
+%YAML 1.1
+--- 
+sample: b
+bad: "unterminated
+`
+	_, err := LoadAllValuesBytes([]byte(stream))
+	e, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if e.Line < 8 {
+		t.Errorf("expected error in the last document, got line %d", e.Line)
+	}
+
+	stream = stream[:len(stream)-len("bad: \"unterminated\n")]
+	all, err := LoadAllValuesBytes([]byte(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Value
+	for _, v := range all {
+		got = append(got, v["sample"])
+	}
+	if !reflect.DeepEqual(got, []Value{"a", "b"}) {
+		t.Errorf("unexpected documents: %v", got)
+	}
+}